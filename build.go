@@ -1,8 +1,12 @@
 package gomodvendor
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/paketo-buildpacks/packit/v2"
@@ -14,30 +18,34 @@ import (
 
 //go:generate faux --interface SBOMGenerator --output fakes/sbom_generator.go
 type SBOMGenerator interface {
-	Generate(dir string) (sbom.SBOM, error)
+	Generate(paths []string, localModules []LocalReplacement, toolModules []string) (sbom.SBOM, error)
 }
 
 //go:generate faux --interface BuildProcess --output fakes/build_process.go
 type BuildProcess interface {
-	ShouldRun(workingDir string) (ok bool, reason string, err error)
-	Execute(path, workingDir string) error
+	ShouldRun(workingDir string, config BuildConfiguration) (ok bool, reason string, err error)
+	VerifyCache(path, workingDir string, config BuildConfiguration) error
+	CheckTidy(path, workingDir string, config BuildConfiguration) error
+	Execute(path, vendorPath, workingDir string, config BuildConfiguration) error
+	VendorDigest(workingDir string, config BuildConfiguration) (digest VendorDigest, ok bool, err error)
+	LocalReplacements(rootDir, workingDir string, config BuildConfiguration) ([]LocalReplacement, error)
+	ToolModules(path, vendorPath, workingDir string, config BuildConfiguration) ([]string, error)
+	ModCacheSettings(workingDir string, config BuildConfiguration) (ModCacheSettings, error)
+	PruneCache(path string, workingDirs []string, config BuildConfiguration) ([]string, error)
+	VendorKey(workingDir string, config BuildConfiguration) (string, error)
 }
 
-func Build(buildProcess BuildProcess, logs scribe.Emitter, clock chronos.Clock, sbomGenerator SBOMGenerator) packit.BuildFunc {
+func Build(configParser ConfigurationParser, buildProcess BuildProcess, logs scribe.Emitter, clock chronos.Clock, sbomGenerator SBOMGenerator, bindingResolver BindingResolver) packit.BuildFunc {
 	return func(context packit.BuildContext) (packit.BuildResult, error) {
 		logs.Title("%s %s", context.BuildpackInfo.Name, context.BuildpackInfo.Version)
 
-		ok, reason, err := buildProcess.ShouldRun(context.WorkingDir)
+		config, err := configParser.Parse(context.WorkingDir)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		if !ok {
-			logs.Process("Skipping build process: %s", reason)
-			logs.Break()
-
-			return packit.BuildResult{}, nil
-		}
+		download := config.VendorMode == VendorModeDownload
+		modules := config.Modules()
 
 		modCacheLayer, err := context.Layers.Get("mod-cache")
 		if err != nil {
@@ -46,25 +54,456 @@ func Build(buildProcess BuildProcess, logs scribe.Emitter, clock chronos.Clock,
 
 		modCacheLayer.Cache = true
 
-		err = buildProcess.Execute(modCacheLayer.Path, context.WorkingDir)
+		// In download mode, the module cache replaces the vendor directory, so
+		// it is made available to the buildpacks that compile the app.
+		modCacheLayer.Build = download
+		modCacheLayer.BuildEnv = packit.Environment{}
+		if download {
+			modCacheLayer.BuildEnv.Override("GOMODCACHE", modCacheLayer.Path)
+		}
+
+		// The vendor-cache layer keeps a snapshot of each vendor directory, so
+		// that a later build with the same module files and Go version restores
+		// it instead of running the go command. Snapshots of the modules that a
+		// build does not vendor are kept for the next one.
+		vendorCacheLayer, err := context.Layers.Get("vendor-cache")
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		logs.GeneratingSBOM(filepath.Join(context.WorkingDir, "go.mod"))
+		vendorCacheLayer.Cache = true
+		snapshots := vendorSnapshots(vendorCacheLayer.Metadata)
+
+		// The layers are kept as is when nothing is vendored, so that the module
+		// cache and the records of previous builds survive.
+		if config.VendorMode == VendorModeNone {
+			logs.Process("Skipping build process: BP_GO_MOD_VENDOR_MODE is '%s'", VendorModeNone)
+			logs.Break()
+
+			layers, err := keptLayers(modCacheLayer, vendorCacheLayer, snapshots, modules)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			return packit.BuildResult{Layers: layers}, nil
+		}
+
+		// The digest of every vendor directory is recorded so that a later
+		// build can tell when one was edited by hand.
+		digests := vendorDigests(modCacheLayer.Metadata)
+
+		var modulePaths []string
+		for _, path := range modules {
+			moduleDir := filepath.Join(context.WorkingDir, path)
+
+			ok, reason, err := buildProcess.ShouldRun(moduleDir, config)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if !ok {
+				if len(modules) > 1 {
+					logs.Process("Skipping build process for '%s': %s", path, reason)
+				} else {
+					logs.Process("Skipping build process: %s", reason)
+				}
 
-		exists, err := fs.Exists(filepath.Join(context.WorkingDir, "go.mod"))
+				err = checkVendorDigest(buildProcess, digests, path, moduleDir, len(modules) > 1, config, logs)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+				continue
+			}
+
+			modulePaths = append(modulePaths, path)
+		}
+
+		if modCacheLayer.Metadata == nil {
+			modCacheLayer.Metadata = map[string]interface{}{}
+		}
+
+		if len(digests) > 0 {
+			modCacheLayer.Metadata["vendor-digests"] = digests
+		}
+
+		// The module cache is kept as is when no go command runs, so that a
+		// build of an already vendored app does not drop it.
+		if len(modulePaths) == 0 && !config.CheckTidy {
+			logs.Break()
+
+			layers, err := keptLayers(modCacheLayer, vendorCacheLayer, snapshots, modules)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			return packit.BuildResult{Layers: layers}, nil
+		}
+
+		// Builds that cannot reach a module proxy download modules from the
+		// go-mod-cache service bindings instead.
+		bindings, err := bindingResolver.Resolve(ModuleCacheBindingType, "", context.Platform.Path)
 		if err != nil {
-			return packit.BuildResult{}, err
+			return packit.BuildResult{}, fmt.Errorf("failed to resolve the %s bindings: %w", ModuleCacheBindingType, err)
 		}
 
-		if !exists {
-			return packit.BuildResult{}, fmt.Errorf("failed to generate SBOM: '%s' does not exist", filepath.Join(context.WorkingDir, "go.mod"))
+		if len(bindings) > 0 {
+			// Archives are extracted to the same directory on every build, so
+			// that the GOPROXY recorded with the module cache settings only
+			// changes with the bindings.
+			tmpDir := filepath.Join(os.TempDir(), "go-mod-cache-bindings")
+			err = os.RemoveAll(tmpDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			err = os.MkdirAll(tmpDir, os.ModePerm)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			defer os.RemoveAll(tmpDir)
+
+			logs.Process("Using modules from the %s bindings", ModuleCacheBindingType)
+			for _, binding := range bindings {
+				dir, err := ModuleProxyDir(binding, tmpDir)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				logs.Subprocess(binding.Name)
+				config.ModuleProxies = append(config.ModuleProxies, dir)
+			}
+			logs.Break()
+		}
+
+		// A module cache written by another version of Go, from another proxy,
+		// with other flags or by another version of the buildpack is emptied
+		// rather than reused. The settings are read in every module, as a
+		// toolchain directive selects the version of Go per module.
+		var moduleSettings []ModCacheSettings
+		for _, path := range modules {
+			settings, err := buildProcess.ModCacheSettings(filepath.Join(context.WorkingDir, path), config)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			moduleSettings = append(moduleSettings, settings)
+		}
+
+		settings := combineModCacheSettings(moduleSettings)
+		settings.BuildpackVersion = context.BuildpackInfo.Version
+
+		if reason, changed := settings.Changed(modCacheLayer.Metadata); changed {
+			logs.Process("Resetting the module cache: %s", reason)
+			logs.Break()
+
+			_, err = removeTree(modCacheLayer.Path)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to remove the module cache: %w", err)
+			}
+
+			err = os.MkdirAll(modCacheLayer.Path, os.ModePerm)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		for key, value := range settings.Metadata() {
+			modCacheLayer.Metadata[key] = value
+		}
+
+		// Directory replacements are checked before any go command runs, as
+		// the go command reports a missing directory with a stat error that
+		// does not name the replace directive.
+		checked := modulePaths
+		if config.CheckTidy {
+			checked = modules
+		}
+
+		localReplacements := map[string][]LocalReplacement{}
+		for _, path := range checked {
+			replacements, err := buildProcess.LocalReplacements(context.WorkingDir, filepath.Join(context.WorkingDir, path), config)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if len(replacements) == 0 {
+				continue
+			}
+
+			localReplacements[path] = replacements
+
+			if len(modules) > 1 {
+				logs.Process("Using local replacements for '%s'", path)
+			} else {
+				logs.Process("Using local replacements")
+			}
+
+			for _, replacement := range replacements {
+				logs.Subprocess("%s", replacement)
+			}
+			logs.Break()
+		}
+
+		// When several modules are vendored, each one is given its own module
+		// cache inside the layer so that they do not share downloads.
+		// Downloaded modules share a single cache, as GOMODCACHE can only name
+		// one directory at build time.
+		modCachePath := func(path string) string {
+			if len(modules) > 1 && !download {
+				return filepath.Join(modCacheLayer.Path, cacheNamespace(path))
+			}
+
+			return modCacheLayer.Path
+		}
+
+		if config.VerifyCache {
+			verified := modulePaths
+			if config.CheckTidy {
+				verified = modules
+			}
+
+			for _, path := range verified {
+				err = buildProcess.VerifyCache(modCachePath(path), filepath.Join(context.WorkingDir, path), config)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+		}
+
+		// The tidiness check also covers modules that are already vendored.
+		if config.CheckTidy {
+			for _, path := range modules {
+				err = buildProcess.CheckTidy(modCachePath(path), filepath.Join(context.WorkingDir, path), config)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+		}
+
+		if len(modulePaths) == 0 {
+			layers, err := keptLayers(modCacheLayer, vendorCacheLayer, snapshots, modules)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			return packit.BuildResult{Layers: layers}, nil
+		}
+
+		// The vendor layer mirrors the working directory, holding the vendor
+		// directory of each module under the path of the module.
+		var vendorLayer packit.Layer
+		useVendorLayer := config.VendorLayer && !download
+		if useVendorLayer {
+			for _, path := range modulePaths {
+				err = checkVendorLink(filepath.Join(context.WorkingDir, path, "vendor"))
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			vendorLayer, err = context.Layers.Get("vendor")
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			vendorLayer, err = vendorLayer.Reset()
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			vendorLayer.Build = true
+			vendorLayer.BuildEnv.Append("GOFLAGS", "-mod=vendor", " ")
+			vendorLayer.BuildEnv.Override("GO_MOD_VENDOR_ROOT", vendorLayer.Path)
+		}
+
+		vendorPath := func(path string) string {
+			if useVendorLayer {
+				return filepath.Join(vendorLayer.Path, path, "vendor")
+			}
+
+			return filepath.Join(context.WorkingDir, path, "vendor")
+		}
+
+		// The snapshots taken by this build replace those of the modules it
+		// vendors.
+		current := map[string]string{}
+		for path, key := range snapshots {
+			if !slices.Contains(modulePaths, path) || download {
+				current[path] = key
+			}
+		}
+
+		var sbomPaths []string
+		var sbomLocalModules []LocalReplacement
+		var sbomToolModules []string
+		for _, path := range modulePaths {
+			moduleDir := filepath.Join(context.WorkingDir, path)
+
+			if len(modules) > 1 {
+				if download {
+					logs.Process("Downloading modules for '%s'", path)
+				} else {
+					logs.Process("Vendoring module '%s'", path)
+				}
+			}
+
+			// The content of a local replacement is not part of the key, so those
+			// modules are always vendored again.
+			var key string
+			if !download && len(localReplacements[path]) == 0 {
+				key, err = buildProcess.VendorKey(moduleDir, config)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			snapshotDir := filepath.Join(vendorCacheLayer.Path, cacheNamespace(path))
+
+			var restored bool
+			if key != "" && snapshots[path] == key {
+				restored, err = RestoreVendorSnapshot(snapshotDir, vendorPath(path))
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			if restored {
+				logs.Process("Reusing cached vendor tree")
+				logs.Break()
+			} else {
+				err = buildProcess.Execute(modCachePath(path), vendorPath(path), moduleDir, config)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				if key != "" {
+					err = SaveVendorSnapshot(vendorPath(path), snapshotDir)
+					if err != nil {
+						return packit.BuildResult{}, err
+					}
+				}
+			}
+
+			if key != "" {
+				current[path] = key
+			}
+
+			// The go command only reads the vendor directory at the root of the
+			// module, so it is linked to the one in the layer. The link dangles at
+			// launch, where the layer is not available.
+			if useVendorLayer {
+				err = linkVendorDir(vendorPath(path), filepath.Join(moduleDir, "vendor"))
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			// A vendor layer is written again on every build, so it cannot be
+			// edited by hand.
+			if !download && !useVendorLayer {
+				digest, ok, err := buildProcess.VendorDigest(moduleDir, config)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				if ok {
+					digests[path] = digest
+					modCacheLayer.Metadata["vendor-digests"] = digests
+
+					if len(modules) > 1 {
+						logs.Process("Vendor digest for '%s': %s", path, digest.Digest)
+					} else {
+						logs.Process("Vendor digest: %s", digest.Digest)
+					}
+					logs.Break()
+				}
+			}
+
+			// A workspace has a go.mod file per module, so the whole workspace is
+			// scanned rather than a single go.mod file.
+			sbomPath := filepath.Join(moduleDir, GoModLocation)
+			_, isWorkspace, err := findWorkspace(moduleDir, config)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if isWorkspace {
+				sbomPath = moduleDir
+			}
+
+			exists, err := fs.Exists(sbomPath)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if !exists {
+				return packit.BuildResult{}, fmt.Errorf("failed to generate SBOM: '%s' does not exist", sbomPath)
+			}
+
+			sbomPaths = append(sbomPaths, sbomPath)
+			sbomLocalModules = append(sbomLocalModules, localReplacements[path]...)
+
+			// Downloaded modules are read from the module cache.
+			var toolVendorPath string
+			if !download {
+				toolVendorPath = vendorPath(path)
+			}
+
+			tools, err := buildProcess.ToolModules(modCachePath(path), toolVendorPath, moduleDir, config)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			for _, tool := range tools {
+				if !slices.Contains(sbomToolModules, tool) {
+					sbomToolModules = append(sbomToolModules, tool)
+				}
+			}
+		}
+
+		// Module versions that go.sum no longer refers to are removed, so that
+		// the cached layer does not keep every version ever downloaded. The
+		// modules that share a module cache are pruned together.
+		var cachePaths []string
+		cacheModules := map[string][]string{}
+		for _, path := range modulePaths {
+			cachePath := modCachePath(path)
+			if _, ok := cacheModules[cachePath]; !ok {
+				cachePaths = append(cachePaths, cachePath)
+			}
+
+			cacheModules[cachePath] = append(cacheModules[cachePath], filepath.Join(context.WorkingDir, path))
+		}
+
+		resolved := map[string][]string{}
+		for _, cachePath := range cachePaths {
+			resolved[cachePath], err = buildProcess.PruneCache(cachePath, cacheModules[cachePath], config)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		if config.CacheMaxSize > 0 {
+			var caches []string
+			for _, path := range modules {
+				if !slices.Contains(caches, modCachePath(path)) {
+					caches = append(caches, modCachePath(path))
+				}
+			}
+
+			err = limitModCache(modCacheLayer, caches, resolved, download, config.CacheMaxSize, clock.Now(), logs)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		for _, path := range sbomPaths {
+			logs.GeneratingSBOM(path)
 		}
 
 		var sbomContent sbom.SBOM
 		duration, err := clock.Measure(func() error {
-			sbomContent, err = sbomGenerator.Generate(filepath.Join(context.WorkingDir, "go.mod"))
+			sbomContent, err = sbomGenerator.Generate(sbomPaths, sbomLocalModules, sbomToolModules)
 			return err
 		})
 		if err != nil {
@@ -81,18 +520,26 @@ func Build(buildProcess BuildProcess, logs scribe.Emitter, clock chronos.Clock,
 			return packit.BuildResult{}, err
 		}
 
-		var layers []packit.Layer
-		exists, err = fs.Exists(modCacheLayer.Path)
+		layers, err := cacheLayers(modCacheLayer)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		if exists {
-			if !fs.IsEmptyDir(modCacheLayer.Path) {
-				layers = append(layers, modCacheLayer)
-			}
+		if download && len(layers) > 0 {
+			logs.EnvironmentVariables(modCacheLayer)
 		}
 
+		if useVendorLayer {
+			layers = append(layers, vendorLayer)
+			logs.EnvironmentVariables(vendorLayer)
+		}
+
+		snapshotLayers, err := vendorCacheLayers(vendorCacheLayer, current, modules)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		layers = append(layers, snapshotLayers...)
+
 		return packit.BuildResult{
 			Plan:   context.Plan,
 			Layers: layers,
@@ -100,3 +547,236 @@ func Build(buildProcess BuildProcess, logs scribe.Emitter, clock chronos.Clock,
 		}, nil
 	}
 }
+
+// checkVendorDigest compares the vendor directory of a module that is not
+// vendored again with the digest recorded by a previous build. A difference
+// while go.mod, go.sum and vendor/modules.txt are unchanged means the vendor
+// directory was edited by hand. Otherwise, the current digest is recorded for
+// the next build.
+func checkVendorDigest(buildProcess BuildProcess, digests map[string]VendorDigest, path, moduleDir string, several bool, config BuildConfiguration, logs scribe.Emitter) error {
+	current, ok, err := buildProcess.VendorDigest(moduleDir, config)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	stored, found := digests[path]
+	if !found || stored.Inputs != current.Inputs || stored.Digest == current.Digest {
+		digests[path] = current
+		return nil
+	}
+
+	vendorDir := "the vendor directory"
+	if several {
+		vendorDir = fmt.Sprintf("the vendor directory of '%s'", path)
+	}
+
+	changed := stored.Changed(current)
+	if config.VendorEdits == VendorEditsFail {
+		return fmt.Errorf("%s was edited since it was generated from the current go.mod and go.sum:\n  %s\n\nrun 'go mod vendor' and commit the result, or set BP_GO_MOD_VENDOR_EDITS=%s to only log a warning", vendorDir, strings.Join(changed, "\n  "), VendorEditsWarn)
+	}
+
+	// The digest recorded before the edits is kept, so that the warning is
+	// repeated until the vendor directory is regenerated.
+	logs.Process("WARNING: %s was edited since it was generated from the current go.mod and go.sum:", vendorDir)
+	for _, module := range changed {
+		logs.Subprocess("%s", module)
+	}
+	logs.Break()
+
+	return nil
+}
+
+// limitModCache records when each module version of the mod-cache layer was
+// last used and evicts the least recently used ones until the layer fits in
+// maxSize bytes. The module versions that this build resolved, keyed by the
+// cache that holds them, are used now, while the others keep the time
+// recorded by a previous build. In download mode, later buildpacks compile
+// against the caches used by this build, so they are never evicted.
+func limitModCache(layer packit.Layer, caches []string, resolved map[string][]string, download bool, maxSize int64, now time.Time, logs scribe.Emitter) error {
+	lastUsed := moduleLastUsed(layer.Metadata)
+
+	var modules []CachedModule
+	keep := map[string]bool{}
+	for _, cache := range caches {
+		exists, err := fs.Exists(cache)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		listed, err := ListModuleCache(layer.Path, cache)
+		if err != nil {
+			return err
+		}
+
+		versions, used := resolved[cache]
+		for _, module := range listed {
+			module.LastUsed = lastUsed[module.Key]
+			if slices.Contains(versions, module.Version) {
+				module.LastUsed = now
+			}
+
+			if used && download {
+				keep[module.Key] = true
+			}
+
+			modules = append(modules, module)
+		}
+	}
+
+	evicted, size, err := EvictModuleCache(modules, keep, maxSize)
+	if err != nil {
+		return err
+	}
+
+	removed := map[string]bool{}
+	if len(evicted) > 0 {
+		logs.Debug.Process("Evicting module versions to fit the module cache in %s", formatBytes(maxSize))
+		for _, module := range evicted {
+			removed[module.Key] = true
+
+			when := "never"
+			if !module.LastUsed.IsZero() {
+				when = module.LastUsed.Format(time.RFC3339)
+			}
+			logs.Debug.Subprocess("%s (%s, last used %s)", module.Key, formatBytes(module.Size), when)
+		}
+		logs.Debug.Break()
+	}
+
+	if size > maxSize {
+		logs.Process("WARNING: the module cache holds %s, more than BP_GO_MOD_CACHE_MAX_SIZE, as the modules downloaded for this build cannot be evicted", formatBytes(size))
+		logs.Break()
+	}
+
+	record := map[string]string{}
+	for _, module := range modules {
+		if !removed[module.Key] && !module.LastUsed.IsZero() {
+			record[module.Key] = module.LastUsed.Format(time.RFC3339)
+		}
+	}
+	layer.Metadata["last-used"] = record
+
+	return nil
+}
+
+// cacheLayers returns the mod-cache layer, unless nothing was downloaded into
+// it and it records no vendor digests.
+func cacheLayers(layer packit.Layer) ([]packit.Layer, error) {
+	exists, err := fs.Exists(layer.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists || fs.IsEmptyDir(layer.Path) {
+		if _, ok := layer.Metadata["vendor-digests"]; !ok {
+			return nil, nil
+		}
+
+		err = os.MkdirAll(layer.Path, os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []packit.Layer{layer}, nil
+}
+
+// keptLayers returns the cached layers of a build that returns before
+// vendoring: the mod-cache layer, unless it is empty, and the vendor-cache
+// layer with the snapshots of previous builds.
+func keptLayers(modCacheLayer, vendorCacheLayer packit.Layer, snapshots map[string]string, modules []string) ([]packit.Layer, error) {
+	layers, err := cacheLayers(modCacheLayer)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotLayers, err := vendorCacheLayers(vendorCacheLayer, snapshots, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(layers, snapshotLayers...), nil
+}
+
+// vendorCacheLayers returns the vendor-cache layer with the given snapshots,
+// leaving out those of modules that are no longer configured, unless no
+// snapshot is left.
+func vendorCacheLayers(layer packit.Layer, snapshots map[string]string, modules []string) ([]packit.Layer, error) {
+	kept := map[string]string{}
+	for path, key := range snapshots {
+		if slices.Contains(modules, path) {
+			kept[path] = key
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil, nil
+	}
+
+	err := pruneVendorSnapshots(layer.Path, kept)
+	if err != nil {
+		return nil, err
+	}
+
+	layer.Metadata = map[string]interface{}{"vendor-snapshots": kept}
+
+	return []packit.Layer{layer}, nil
+}
+
+// linkVendorDir links the vendor directory of a module to the one written
+// into the vendor layer, replacing a link left by a previous build. Nothing
+// is linked when the go command wrote no vendor directory.
+func linkVendorDir(vendorDir, link string) error {
+	exists, err := fs.Exists(vendorDir)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	err = checkVendorLink(link)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(link)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.Symlink(vendorDir, link)
+}
+
+// checkVendorLink fails when the vendor directory of a module is a real
+// directory, committed to the app, rather than a link into the vendor layer.
+func checkVendorLink(link string) error {
+	info, err := os.Lstat(link)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("BP_GO_MOD_VENDOR_LAYER cannot replace the vendor directory committed to the app at '%s': remove it from the app or unset BP_GO_MOD_VENDOR_LAYER", link)
+	}
+
+	return nil
+}
+
+// cacheNamespace returns the name of the directory inside the mod-cache layer
+// that holds the module cache of the module at the given path.
+func cacheNamespace(path string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(path)))[:16]
+}
@@ -0,0 +1,350 @@
+package gomodvendor
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:generate faux --interface ConfigurationParser --output fakes/configuration_parser.go
+type ConfigurationParser interface {
+	Parse(workingDir string) (BuildConfiguration, error)
+}
+
+// BuildConfiguration holds the settings read from BP_* environment variables
+// that change how the buildpack detects and vendors modules.
+type BuildConfiguration struct {
+	// ModulePaths are the directories containing a go.mod (or go.work) file,
+	// relative to the working directory. Each module is vendored separately.
+	ModulePaths []string
+
+	// GoVersion is the version of Go to request instead of the one derived
+	// from go.mod.
+	GoVersion string
+
+	// DisableWorkspace ignores any go.work file, as if GOWORK=off were set.
+	DisableWorkspace bool
+
+	// MigrateLegacy generates go.mod and go.sum from the lock file of a
+	// legacy dependency manager (dep or glide) when go.mod is missing.
+	MigrateLegacy bool
+
+	// MigrateModulePath is the module path given to go mod init during a
+	// migration. When empty, it is read from the legacy manifest if possible.
+	MigrateModulePath string
+
+	// VendorMismatch decides what happens when an existing vendor directory
+	// is inconsistent with go.mod: VendorMismatchRevendor, the default, logs
+	// the inconsistencies and regenerates the vendor directory, while
+	// VendorMismatchFail fails the build.
+	VendorMismatch string
+
+	// VendorMode selects the build action: VendorModeVendor runs go mod
+	// vendor, VendorModeDownload only fills the module cache with go mod
+	// download and VendorModeNone does nothing.
+	VendorMode string
+
+	// Revendor regenerates an existing vendor directory even when it is
+	// consistent with go.mod.
+	Revendor bool
+
+	// CheckTidy fails the build when go mod tidy would change go.mod or
+	// go.sum.
+	CheckTidy bool
+
+	// Strict fails before vendoring when go.sum lacks an entry for a
+	// required module, and fails the build when the go command changes go.mod
+	// or go.sum.
+	Strict bool
+
+	// VerifyCache runs go mod verify against the module cache before it is
+	// used, and downloads every module again if it was modified.
+	VerifyCache bool
+
+	// VendorEdits decides what happens when a vendor directory differs from
+	// the one recorded by a previous build for the same go.mod and go.sum:
+	// VendorEditsWarn logs a warning and VendorEditsFail fails the build.
+	VendorEdits string
+
+	// ExcludeTools leaves the packages named by tool directives, and the
+	// modules only they need, out of the vendor directory.
+	ExcludeTools bool
+
+	// VendorLayer writes the vendor directory into a layer that is only
+	// available at build time, instead of the working directory.
+	VendorLayer bool
+
+	// CacheMaxSize is the size in bytes above which the least recently used
+	// module versions are evicted from the mod-cache layer. Zero means no
+	// limit.
+	CacheMaxSize int64
+
+	// ModuleProxies are directories in GOPROXY layout that the go command
+	// downloads modules from instead of the network. They are not read from
+	// the environment: Build sets them from the go-mod-cache service
+	// bindings.
+	ModuleProxies []string
+}
+
+const (
+	VendorMismatchFail     = "fail"
+	VendorMismatchRevendor = "revendor"
+
+	VendorModeVendor   = "vendor"
+	VendorModeDownload = "download"
+	VendorModeNone     = "none"
+
+	VendorEditsWarn = "warn"
+	VendorEditsFail = "fail"
+)
+
+// Modules returns the configured module paths, defaulting to the working
+// directory itself.
+func (c BuildConfiguration) Modules() []string {
+	if len(c.ModulePaths) == 0 {
+		return []string{"."}
+	}
+
+	return c.ModulePaths
+}
+
+type BuildConfigurationParser struct{}
+
+func NewBuildConfigurationParser() BuildConfigurationParser {
+	return BuildConfigurationParser{}
+}
+
+func (p BuildConfigurationParser) Parse(workingDir string) (BuildConfiguration, error) {
+	config := BuildConfiguration{
+		ModulePaths:    []string{"."},
+		VendorMismatch: VendorMismatchRevendor,
+		VendorMode:     VendorModeVendor,
+		VendorEdits:    VendorEditsWarn,
+	}
+
+	modPath := os.Getenv("BP_GO_MOD_PATH")
+	modules := os.Getenv("BP_GO_MODULES")
+
+	if modPath != "" && modules != "" {
+		return BuildConfiguration{}, fmt.Errorf("BP_GO_MOD_PATH and BP_GO_MODULES cannot both be set")
+	}
+
+	if modPath != "" {
+		path, err := parseModulePath(workingDir, modPath)
+		if err != nil {
+			return BuildConfiguration{}, fmt.Errorf("failed to parse BP_GO_MOD_PATH: %w", err)
+		}
+
+		config.ModulePaths = []string{path}
+	}
+
+	if modules != "" {
+		config.ModulePaths = nil
+		for _, value := range strings.Split(modules, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			path, err := parseModulePath(workingDir, value)
+			if err != nil {
+				return BuildConfiguration{}, fmt.Errorf("failed to parse BP_GO_MODULES: %w", err)
+			}
+
+			if !slices.Contains(config.ModulePaths, path) {
+				config.ModulePaths = append(config.ModulePaths, path)
+			}
+		}
+
+		if len(config.ModulePaths) == 0 {
+			return BuildConfiguration{}, fmt.Errorf("failed to parse BP_GO_MODULES: no modules listed in '%s'", modules)
+		}
+	}
+
+	config.GoVersion = os.Getenv("BP_GO_VERSION")
+
+	var err error
+	config.DisableWorkspace, err = parseBool("BP_GO_WORK_DISABLED")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	config.MigrateLegacy, err = parseBool("BP_GO_MOD_MIGRATE")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	config.MigrateModulePath = os.Getenv("BP_GO_MOD_MIGRATE_MODULE")
+
+	config.Revendor, err = parseBool("BP_GO_MOD_REVENDOR")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	config.CheckTidy, err = parseBool("BP_GO_MOD_TIDY_CHECK")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	config.Strict, err = parseBool("BP_GO_MOD_STRICT")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	config.VerifyCache, err = parseBool("BP_GO_MOD_VERIFY")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	config.ExcludeTools, err = parseBool("BP_GO_MOD_EXCLUDE_TOOLS")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	config.VendorLayer, err = parseBool("BP_GO_MOD_VENDOR_LAYER")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	config.CacheMaxSize, err = parseSize("BP_GO_MOD_CACHE_MAX_SIZE")
+	if err != nil {
+		return BuildConfiguration{}, err
+	}
+
+	if edits := os.Getenv("BP_GO_MOD_VENDOR_EDITS"); edits != "" {
+		if edits != VendorEditsWarn && edits != VendorEditsFail {
+			return BuildConfiguration{}, fmt.Errorf("failed to parse BP_GO_MOD_VENDOR_EDITS: '%s' is not one of '%s' or '%s'", edits, VendorEditsWarn, VendorEditsFail)
+		}
+
+		config.VendorEdits = edits
+	}
+
+	if mismatch := os.Getenv("BP_GO_MOD_VENDOR_MISMATCH"); mismatch != "" {
+		if mismatch != VendorMismatchFail && mismatch != VendorMismatchRevendor {
+			return BuildConfiguration{}, fmt.Errorf("failed to parse BP_GO_MOD_VENDOR_MISMATCH: '%s' is not one of '%s' or '%s'", mismatch, VendorMismatchFail, VendorMismatchRevendor)
+		}
+
+		config.VendorMismatch = mismatch
+	}
+
+	if mode := os.Getenv("BP_GO_MOD_VENDOR_MODE"); mode != "" {
+		if !slices.Contains([]string{VendorModeVendor, VendorModeDownload, VendorModeNone}, mode) {
+			return BuildConfiguration{}, fmt.Errorf("failed to parse BP_GO_MOD_VENDOR_MODE: '%s' is not one of '%s', '%s' or '%s'", mode, VendorModeVendor, VendorModeDownload, VendorModeNone)
+		}
+
+		config.VendorMode = mode
+	}
+
+	return config, nil
+}
+
+// parseModulePath cleans the given path and checks that it names an existing
+// directory inside the working directory, following symlinks.
+func parseModulePath(workingDir, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(workingDir, path)
+		if err != nil {
+			return "", err
+		}
+		path = rel
+	}
+
+	path = filepath.Clean(path)
+	if path == ".." || strings.HasPrefix(path, "../") {
+		return "", fmt.Errorf("'%s' is outside of the working directory", path)
+	}
+
+	info, err := os.Stat(filepath.Join(workingDir, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("'%s' does not exist in the working directory", path)
+		}
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return "", fmt.Errorf("'%s' is not a directory", path)
+	}
+
+	root, err := filepath.EvalSymlinks(workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(workingDir, path))
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return "", err
+	}
+
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("'%s' is outside of the working directory", path)
+	}
+
+	return path, nil
+}
+
+func parseBool(name string) (bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return b, nil
+}
+
+// parseSize reads a size in bytes, either as a plain number or with a binary
+// unit such as 512M, 2GB or 2GiB.
+func parseSize(name string) (int64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	number := strings.ToUpper(strings.TrimSpace(value))
+
+	multiplier := int64(1)
+	for i, unit := range []string{"K", "M", "G", "T"} {
+		if trimmed, ok := trimUnit(number, unit); ok {
+			number = trimmed
+			multiplier = int64(1) << (10 * (i + 1))
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("failed to parse %s: '%s' is not a size, such as 512M or 2G", name, value)
+	}
+
+	if size > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("failed to parse %s: '%s' is too large", name, value)
+	}
+
+	return size * multiplier, nil
+}
+
+// trimUnit removes the unit from the end of an upper-case size, written as
+// K, KB or KIB for the K unit.
+func trimUnit(size, unit string) (string, bool) {
+	for _, suffix := range []string{unit + "IB", unit + "B", unit} {
+		if strings.HasSuffix(size, suffix) {
+			return strings.TrimSuffix(size, suffix), true
+		}
+	}
+
+	return size, false
+}
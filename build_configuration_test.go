@@ -0,0 +1,552 @@
+package gomodvendor_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testBuildConfigurationParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		parser     gomodvendor.BuildConfigurationParser
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		parser = gomodvendor.NewBuildConfigurationParser()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("returns the default configuration", func() {
+		config, err := parser.Parse(workingDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal(gomodvendor.BuildConfiguration{
+			ModulePaths:    []string{"."},
+			VendorMismatch: "revendor",
+			VendorMode:     "vendor",
+			VendorEdits:    "warn",
+		}))
+		Expect(config.Modules()).To(Equal([]string{"."}))
+	})
+
+	context("when BP_GO_MOD_PATH is set", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, "services", "api"), os.ModePerm)).To(Succeed())
+			Expect(os.Setenv("BP_GO_MOD_PATH", "./services/api/")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_PATH")).To(Succeed())
+		})
+
+		it("sets the module path", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.ModulePaths).To(Equal([]string{"services/api"}))
+		})
+
+		context("when the path is absolute and inside the working directory", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_GO_MOD_PATH", filepath.Join(workingDir, "services", "api"))).To(Succeed())
+			})
+
+			it("sets the module path relative to the working directory", func() {
+				config, err := parser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.ModulePaths).To(Equal([]string{"services/api"}))
+			})
+		})
+	})
+
+	context("when BP_GO_MODULES is set", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+			Expect(os.Setenv("BP_GO_MODULES", "./, ./tools,tools/,")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MODULES")).To(Succeed())
+		})
+
+		it("sets each module path once", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.ModulePaths).To(Equal([]string{".", "tools"}))
+		})
+	})
+
+	context("when BP_GO_VERSION is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_VERSION", "1.22.5")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_VERSION")).To(Succeed())
+		})
+
+		it("sets the go version", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.GoVersion).To(Equal("1.22.5"))
+		})
+	})
+
+	context("when BP_GO_WORK_DISABLED is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_WORK_DISABLED", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_WORK_DISABLED")).To(Succeed())
+		})
+
+		it("disables workspaces", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.DisableWorkspace).To(BeTrue())
+		})
+	})
+
+	context("when BP_GO_MOD_MIGRATE and BP_GO_MOD_MIGRATE_MODULE are set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_MIGRATE", "true")).To(Succeed())
+			Expect(os.Setenv("BP_GO_MOD_MIGRATE_MODULE", "github.com/some-org/some-app")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_MIGRATE")).To(Succeed())
+			Expect(os.Unsetenv("BP_GO_MOD_MIGRATE_MODULE")).To(Succeed())
+		})
+
+		it("enables the migration with the given module path", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.MigrateLegacy).To(BeTrue())
+			Expect(config.MigrateModulePath).To(Equal("github.com/some-org/some-app"))
+		})
+	})
+
+	context("when BP_GO_MOD_VENDOR_MISMATCH is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_VENDOR_MISMATCH", "fail")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_VENDOR_MISMATCH")).To(Succeed())
+		})
+
+		it("sets how an inconsistent vendor directory is handled", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.VendorMismatch).To(Equal("fail"))
+		})
+	})
+
+	context("when BP_GO_MOD_VENDOR_MODE is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_VENDOR_MODE", "download")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_VENDOR_MODE")).To(Succeed())
+		})
+
+		it("sets the vendor mode", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.VendorMode).To(Equal("download"))
+		})
+	})
+
+	context("when BP_GO_MOD_REVENDOR is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_REVENDOR", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_REVENDOR")).To(Succeed())
+		})
+
+		it("forces the vendor directory to be regenerated", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Revendor).To(BeTrue())
+		})
+	})
+
+	context("when BP_GO_MOD_TIDY_CHECK is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_TIDY_CHECK", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_TIDY_CHECK")).To(Succeed())
+		})
+
+		it("enables the tidiness check", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.CheckTidy).To(BeTrue())
+		})
+	})
+
+	context("when BP_GO_MOD_STRICT is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_STRICT", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_STRICT")).To(Succeed())
+		})
+
+		it("enables strict mode", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Strict).To(BeTrue())
+		})
+	})
+
+	context("when BP_GO_MOD_VERIFY is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_VERIFY", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_VERIFY")).To(Succeed())
+		})
+
+		it("enables the module cache verification", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.VerifyCache).To(BeTrue())
+		})
+	})
+
+	context("when BP_GO_MOD_EXCLUDE_TOOLS is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_EXCLUDE_TOOLS", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_EXCLUDE_TOOLS")).To(Succeed())
+		})
+
+		it("excludes tools from the vendor directory", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.ExcludeTools).To(BeTrue())
+		})
+	})
+
+	context("when BP_GO_MOD_VENDOR_LAYER is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_VENDOR_LAYER", "true")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_VENDOR_LAYER")).To(Succeed())
+		})
+
+		it("vendors into a layer", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.VendorLayer).To(BeTrue())
+		})
+	})
+
+	context("when BP_GO_MOD_VENDOR_EDITS is set", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_GO_MOD_VENDOR_EDITS", "fail")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_VENDOR_EDITS")).To(Succeed())
+		})
+
+		it("sets how an edited vendor directory is handled", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.VendorEdits).To(Equal("fail"))
+		})
+	})
+
+	context("when BP_GO_MOD_CACHE_MAX_SIZE is set", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_GO_MOD_CACHE_MAX_SIZE")).To(Succeed())
+		})
+
+		it("reads the size in bytes", func() {
+			for value, size := range map[string]int64{
+				"1048576": 1048576,
+				"512K":    512 << 10,
+				"512M":    512 << 20,
+				"2G":      2 << 30,
+				"2gb":     2 << 30,
+				"2GiB":    2 << 30,
+				"1T":      1 << 40,
+				"3tib":    3 << 40,
+			} {
+				Expect(os.Setenv("BP_GO_MOD_CACHE_MAX_SIZE", value)).To(Succeed())
+
+				config, err := parser.Parse(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.CacheMaxSize).To(Equal(size), value)
+			}
+		})
+	})
+
+	context("failure cases", func() {
+		context("when BP_GO_WORK_DISABLED is not a boolean", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_GO_WORK_DISABLED", "sometimes")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_WORK_DISABLED")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_GO_WORK_DISABLED")))
+			})
+		})
+
+		context("when BP_GO_MOD_MIGRATE is not a boolean", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_GO_MOD_MIGRATE", "maybe")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_MIGRATE")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_GO_MOD_MIGRATE")))
+			})
+		})
+
+		context("when BP_GO_MOD_CACHE_MAX_SIZE is not a size", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_GO_MOD_CACHE_MAX_SIZE", "2 bananas")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_CACHE_MAX_SIZE")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError("failed to parse BP_GO_MOD_CACHE_MAX_SIZE: '2 bananas' is not a size, such as 512M or 2G"))
+			})
+		})
+
+		context("when BP_GO_MOD_CACHE_MAX_SIZE has an unknown unit", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_CACHE_MAX_SIZE")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				for _, value := range []string{"5I", "5B", "5IB", "5GI", "5BG", "5P"} {
+					Expect(os.Setenv("BP_GO_MOD_CACHE_MAX_SIZE", value)).To(Succeed())
+
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError(fmt.Sprintf("failed to parse BP_GO_MOD_CACHE_MAX_SIZE: '%s' is not a size, such as 512M or 2G", value)), value)
+				}
+			})
+		})
+
+		context("when BP_GO_MOD_CACHE_MAX_SIZE is too large", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_CACHE_MAX_SIZE")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				for _, value := range []string{"9999999999T", "8388608T"} {
+					Expect(os.Setenv("BP_GO_MOD_CACHE_MAX_SIZE", value)).To(Succeed())
+
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError(fmt.Sprintf("failed to parse BP_GO_MOD_CACHE_MAX_SIZE: '%s' is too large", value)), value)
+				}
+			})
+		})
+
+		context("when BP_GO_MOD_VENDOR_EDITS has an unknown value", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_GO_MOD_VENDOR_EDITS", "ignore")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_VENDOR_EDITS")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError("failed to parse BP_GO_MOD_VENDOR_EDITS: 'ignore' is not one of 'warn' or 'fail'"))
+			})
+		})
+
+		context("when BP_GO_MOD_VENDOR_MISMATCH has an unknown value", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_GO_MOD_VENDOR_MISMATCH", "ignore")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_VENDOR_MISMATCH")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError("failed to parse BP_GO_MOD_VENDOR_MISMATCH: 'ignore' is not one of 'fail' or 'revendor'"))
+			})
+		})
+
+		context("when BP_GO_MOD_VENDOR_MODE has an unknown value", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_GO_MOD_VENDOR_MODE", "copy")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_VENDOR_MODE")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError("failed to parse BP_GO_MOD_VENDOR_MODE: 'copy' is not one of 'vendor', 'download' or 'none'"))
+			})
+		})
+
+		context("when BP_GO_MOD_PATH and BP_GO_MODULES are both set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_GO_MOD_PATH", ".")).To(Succeed())
+				Expect(os.Setenv("BP_GO_MODULES", ".")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_PATH")).To(Succeed())
+				Expect(os.Unsetenv("BP_GO_MODULES")).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError("BP_GO_MOD_PATH and BP_GO_MODULES cannot both be set"))
+			})
+		})
+
+		context("when BP_GO_MODULES", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MODULES")).To(Succeed())
+			})
+
+			context("lists a missing directory", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_GO_MODULES", ".,./tools")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError("failed to parse BP_GO_MODULES: 'tools' does not exist in the working directory"))
+				})
+			})
+
+			context("does not list any module", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_GO_MODULES", " , ")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError("failed to parse BP_GO_MODULES: no modules listed in ' , '"))
+				})
+			})
+		})
+
+		context("when BP_GO_MOD_PATH", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_GO_MOD_PATH")).To(Succeed())
+			})
+
+			context("does not exist", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_GO_MOD_PATH", "services/missing")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError("failed to parse BP_GO_MOD_PATH: 'services/missing' does not exist in the working directory"))
+				})
+			})
+
+			context("is a file", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), nil, os.ModePerm)).To(Succeed())
+					Expect(os.Setenv("BP_GO_MOD_PATH", "go.mod")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError("failed to parse BP_GO_MOD_PATH: 'go.mod' is not a directory"))
+				})
+			})
+
+			context("escapes the working directory", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_GO_MOD_PATH", "services/../../other")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError("failed to parse BP_GO_MOD_PATH: '../other' is outside of the working directory"))
+				})
+			})
+
+			context("is an absolute path outside the working directory", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_GO_MOD_PATH", "/etc")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError(ContainSubstring("is outside of the working directory")))
+				})
+			})
+
+			context("is a symlink that escapes the working directory", func() {
+				var outside string
+
+				it.Before(func() {
+					var err error
+					outside, err = os.MkdirTemp("", "outside")
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(os.Symlink(outside, filepath.Join(workingDir, "linked"))).To(Succeed())
+					Expect(os.Setenv("BP_GO_MOD_PATH", "linked")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.RemoveAll(outside)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError("failed to parse BP_GO_MOD_PATH: 'linked' is outside of the working directory"))
+				})
+			})
+		})
+	})
+}
@@ -2,10 +2,12 @@ package gomodvendor_test
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"testing"
@@ -18,6 +20,7 @@ import (
 	"github.com/paketo-buildpacks/go-mod-vendor/fakes"
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 	"github.com/sclevine/spec"
 
 	. "github.com/onsi/gomega"
@@ -27,12 +30,15 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
 
-		layersDir     string
-		workingDir    string
-		logs          *bytes.Buffer
-		buildProcess  *fakes.BuildProcess
-		sbomGenerator *fakes.SBOMGenerator
-		clock         chronos.Clock
+		layersDir       string
+		workingDir      string
+		logs            *bytes.Buffer
+		configParser    *fakes.ConfigurationParser
+		buildProcess    *fakes.BuildProcess
+		sbomGenerator   *fakes.SBOMGenerator
+		bindingResolver *fakes.BindingResolver
+		clock           chronos.Clock
+		executions      [][2]string
 
 		build packit.BuildFunc
 	)
@@ -49,6 +55,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		logs = bytes.NewBuffer(nil)
 
+		configParser = &fakes.ConfigurationParser{}
+
 		buildProcess = &fakes.BuildProcess{}
 		buildProcess.ShouldRunCall.Returns.Ok = true
 
@@ -62,14 +70,20 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			return now
 		})
 
+		executions = nil
+
 		sbomGenerator = &fakes.SBOMGenerator{}
 		sbomGenerator.GenerateCall.Returns.SBOM = sbom.SBOM{}
 
+		bindingResolver = &fakes.BindingResolver{}
+
 		build = gomodvendor.Build(
+			configParser,
 			buildProcess,
 			scribe.NewEmitter(logs),
 			clock,
 			sbomGenerator,
+			bindingResolver,
 		)
 	})
 
@@ -95,6 +109,9 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		Expect(layer.Name).To(Equal("mod-cache"))
 		Expect(layer.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+		Expect(layer.Cache).To(BeTrue())
+		Expect(layer.Build).To(BeFalse())
+		Expect(layer.BuildEnv).To(BeEmpty())
 
 		Expect(result.Build.SBOM.Formats()).To(HaveLen(2))
 		cdx := result.Build.SBOM.Formats()[0]
@@ -167,20 +184,20 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		Expect(buildProcess.ExecuteCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
 		Expect(buildProcess.ExecuteCall.Receives.WorkingDir).To(Equal(workingDir))
+		Expect(buildProcess.ExecuteCall.Receives.Config).To(Equal(gomodvendor.BuildConfiguration{}))
 
-		Expect(sbomGenerator.GenerateCall.Receives.Dir).To(Equal(filepath.Join(workingDir, "go.mod")))
+		Expect(sbomGenerator.GenerateCall.Receives.Paths).To(Equal([]string{filepath.Join(workingDir, "go.mod")}))
 
 		Expect(logs.String()).To(ContainSubstring("Some Buildpack some-version"))
 		Expect(logs.String()).NotTo(ContainSubstring("Skipping build process: module graph is empty"))
 	})
 
-	context("when the mod cache layer does not exist", func() {
+	context("when the vendor mode is download", func() {
 		it.Before(func() {
-			err := os.RemoveAll(filepath.Join(layersDir, "mod-cache"))
-			Expect(err).NotTo(HaveOccurred())
+			configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "download"
 		})
 
-		it("does not include the module cache layer in the build result", func() {
+		it("makes the module cache layer available at build time", func() {
 			result, err := build(packit.BuildContext{
 				Layers:     packit.Layers{Path: layersDir},
 				WorkingDir: workingDir,
@@ -191,17 +208,66 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result.Layers).To(BeEmpty())
+			Expect(result.Layers).To(HaveLen(1))
+			layer := result.Layers[0]
+
+			Expect(layer.Name).To(Equal("mod-cache"))
+			Expect(layer.Cache).To(BeTrue())
+			Expect(layer.Build).To(BeTrue())
+			Expect(layer.BuildEnv).To(Equal(packit.Environment{
+				"GOMODCACHE.override": filepath.Join(layersDir, "mod-cache"),
+			}))
+
+			Expect(buildProcess.ExecuteCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+			Expect(sbomGenerator.GenerateCall.Receives.Paths).To(Equal([]string{filepath.Join(workingDir, "go.mod")}))
+
+			Expect(logs.String()).To(ContainSubstring("Configuring build environment"))
+			Expect(logs.String()).To(ContainSubstring(fmt.Sprintf(`GOMODCACHE -> "%s"`, filepath.Join(layersDir, "mod-cache"))))
+		})
+
+		context("when several modules are configured", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+				configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{".", "tools"}
+
+				buildProcess.ExecuteCall.Stub = func(path, vendorPath, workingDir string, config gomodvendor.BuildConfiguration) error {
+					executions = append(executions, [2]string{path, workingDir})
+					return nil
+				}
+			})
+
+			it("downloads every module into the same module cache", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(Equal([][2]string{
+					{filepath.Join(layersDir, "mod-cache"), workingDir},
+					{filepath.Join(layersDir, "mod-cache"), filepath.Join(workingDir, "tools")},
+				}))
+
+				Expect(logs.String()).To(ContainSubstring("Downloading modules for '.'"))
+				Expect(logs.String()).To(ContainSubstring("Downloading modules for 'tools'"))
+			})
 		})
 	})
 
-	context("when the mod cache layer is empty", func() {
+	context("when the vendor mode is none", func() {
 		it.Before(func() {
-			err := os.RemoveAll(filepath.Join(layersDir, "mod-cache", "cache"))
-			Expect(err).NotTo(HaveOccurred())
+			configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "none"
 		})
 
-		it("does not include the module cache layer in the build result", func() {
+		it("does not run the build process and keeps the module cache", func() {
+			Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache.toml"), []byte("[metadata]\n  go-version = \"go1.23.4\"\n"), 0600)).To(Succeed())
+
 			result, err := build(packit.BuildContext{
 				Layers:     packit.Layers{Path: layersDir},
 				WorkingDir: workingDir,
@@ -212,14 +278,47 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(result.Layers).To(BeEmpty())
+			Expect(result.Layers).To(HaveLen(1))
+			layer := result.Layers[0]
+			Expect(layer.Name).To(Equal("mod-cache"))
+			Expect(layer.Cache).To(BeTrue())
+			Expect(layer.Build).To(BeFalse())
+			Expect(layer.Metadata).To(Equal(map[string]interface{}{"go-version": "go1.23.4"}))
+			Expect(result.Build).To(Equal(packit.BuildMetadata{}))
+
+			Expect(buildProcess.ShouldRunCall.CallCount).To(Equal(0))
+			Expect(buildProcess.ExecuteCall.CallCount).To(Equal(0))
+			Expect(sbomGenerator.GenerateCall.CallCount).To(Equal(0))
+
+			Expect(logs.String()).To(ContainSubstring("Skipping build process: BP_GO_MOD_VENDOR_MODE is 'none'"))
 		})
 	})
 
-	context("failure cases", func() {
-		context("build process fails to check if it should run", func() {
+	context("when the module cache verification is enabled", func() {
+		it.Before(func() {
+			configParser.ParseCall.Returns.BuildConfiguration.VerifyCache = true
+		})
+
+		it("verifies the module cache before vendoring", func() {
+			_, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildProcess.VerifyCacheCall.CallCount).To(Equal(1))
+			Expect(buildProcess.VerifyCacheCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+			Expect(buildProcess.VerifyCacheCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(buildProcess.ExecuteCall.CallCount).To(Equal(1))
+		})
+
+		context("when the verification fails", func() {
 			it.Before(func() {
-				buildProcess.ShouldRunCall.Returns.Err = errors.New("build process failed to check")
+				buildProcess.VerifyCacheCall.Returns.Error = errors.New("failed to verify")
 			})
 
 			it("returns an error", func() {
@@ -227,13 +326,59 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					Layers:     packit.Layers{Path: layersDir},
 					WorkingDir: workingDir,
 				})
-				Expect(err).To(MatchError(ContainSubstring("build process failed to check")))
+				Expect(err).To(MatchError("failed to verify"))
+				Expect(buildProcess.ExecuteCall.CallCount).To(Equal(0))
 			})
 		})
+	})
 
-		context("modCacheLayer cannot be retrieved", func() {
+	context("when the tidiness check is enabled", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+			configParser.ParseCall.Returns.BuildConfiguration = gomodvendor.BuildConfiguration{
+				ModulePaths: []string{".", "tools"},
+				CheckTidy:   true,
+			}
+
+			buildProcess.CheckTidyCall.Stub = func(path, workingDir string, config gomodvendor.BuildConfiguration) error {
+				executions = append(executions, [2]string{path, workingDir})
+				return nil
+			}
+
+			buildProcess.ShouldRunCall.Stub = func(workingDir string, config gomodvendor.BuildConfiguration) (bool, string, error) {
+				return false, "modules are already vendored", nil
+			}
+		})
+
+		it("checks every module, including those that are not vendored", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executions).To(HaveLen(2))
+			Expect(executions[0][1]).To(Equal(workingDir))
+			Expect(executions[1][1]).To(Equal(filepath.Join(workingDir, "tools")))
+			Expect(executions[0][0]).NotTo(Equal(executions[1][0]))
+
+			Expect(buildProcess.ExecuteCall.CallCount).To(Equal(0))
+			Expect(sbomGenerator.GenerateCall.CallCount).To(Equal(0))
+
+			Expect(result.Layers).To(HaveLen(1))
+			Expect(result.Layers[0].Name).To(Equal("mod-cache"))
+		})
+
+		context("when the check fails", func() {
 			it.Before(func() {
-				Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache.toml"), nil, 0000)).To(Succeed())
+				buildProcess.CheckTidyCall.Stub = nil
+				buildProcess.CheckTidyCall.Returns.Error = errors.New("go.mod and go.sum are not tidy")
 			})
 
 			it("returns an error", func() {
@@ -241,7 +386,1410 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					Layers:     packit.Layers{Path: layersDir},
 					WorkingDir: workingDir,
 				})
-				Expect(err).To(MatchError(ContainSubstring("failed to parse layer content metadata")))
+				Expect(err).To(MatchError("go.mod and go.sum are not tidy"))
+			})
+		})
+	})
+
+	context("when the module is in a sub-directory", func() {
+		var moduleDir string
+
+		it.Before(func() {
+			moduleDir = filepath.Join(workingDir, "services", "api")
+			Expect(os.MkdirAll(moduleDir, os.ModePerm)).To(Succeed())
+			Expect(os.Rename(filepath.Join(workingDir, "go.mod"), filepath.Join(moduleDir, "go.mod"))).To(Succeed())
+
+			configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{"services/api"}
+		})
+
+		it("vendors and generates the SBOM in that directory", func() {
+			_, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configParser.ParseCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(buildProcess.ShouldRunCall.Receives.WorkingDir).To(Equal(moduleDir))
+			Expect(buildProcess.ExecuteCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+			Expect(buildProcess.ExecuteCall.Receives.WorkingDir).To(Equal(moduleDir))
+			Expect(sbomGenerator.GenerateCall.Receives.Paths).To(Equal([]string{filepath.Join(moduleDir, "go.mod")}))
+		})
+	})
+
+	context("when several modules are configured", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+			configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{".", "tools"}
+
+			buildProcess.ExecuteCall.Stub = func(path, vendorPath, workingDir string, config gomodvendor.BuildConfiguration) error {
+				executions = append(executions, [2]string{path, workingDir})
+				return nil
+			}
+		})
+
+		it("vendors each module with its own module cache", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+			Expect(result.Layers[0].Name).To(Equal("mod-cache"))
+
+			Expect(buildProcess.ShouldRunCall.CallCount).To(Equal(2))
+			Expect(executions).To(HaveLen(2))
+
+			Expect(executions[0][0]).To(HavePrefix(filepath.Join(layersDir, "mod-cache") + "/"))
+			Expect(executions[0][1]).To(Equal(workingDir))
+			Expect(executions[1][0]).To(HavePrefix(filepath.Join(layersDir, "mod-cache") + "/"))
+			Expect(executions[1][1]).To(Equal(filepath.Join(workingDir, "tools")))
+			Expect(executions[0][0]).NotTo(Equal(executions[1][0]))
+
+			Expect(sbomGenerator.GenerateCall.Receives.Paths).To(Equal([]string{
+				filepath.Join(workingDir, "go.mod"),
+				filepath.Join(workingDir, "tools", "go.mod"),
+			}))
+
+			Expect(logs.String()).To(ContainSubstring("Vendoring module '.'"))
+			Expect(logs.String()).To(ContainSubstring("Vendoring module 'tools'"))
+		})
+
+		context("when one of the modules is already vendored", func() {
+			it.Before(func() {
+				buildProcess.ShouldRunCall.Stub = func(dir string, config gomodvendor.BuildConfiguration) (bool, string, error) {
+					if dir == workingDir {
+						return false, "modules are already vendored", nil
+					}
+					return true, "", nil
+				}
+			})
+
+			it("only vendors the other module", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(HaveLen(1))
+				Expect(executions[0][1]).To(Equal(filepath.Join(workingDir, "tools")))
+
+				Expect(sbomGenerator.GenerateCall.Receives.Paths).To(Equal([]string{
+					filepath.Join(workingDir, "tools", "go.mod"),
+				}))
+
+				Expect(logs.String()).To(ContainSubstring("Skipping build process for '.': modules are already vendored"))
+			})
+		})
+
+		context("when every module is already vendored", func() {
+			it.Before(func() {
+				buildProcess.ShouldRunCall.Returns.Ok = false
+				buildProcess.ShouldRunCall.Returns.Reason = "modules are already vendored"
+			})
+
+			it("does not run the build process", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(1))
+				Expect(result.Layers[0].Name).To(Equal("mod-cache"))
+				Expect(result.Build).To(Equal(packit.BuildMetadata{}))
+
+				Expect(executions).To(BeEmpty())
+				Expect(sbomGenerator.GenerateCall.CallCount).To(Equal(0))
+			})
+		})
+	})
+
+	context("when the working directory is a workspace", func() {
+		it.Before(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "go.mod"))).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+		})
+
+		it("generates the SBOM from the whole workspace", func() {
+			_, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sbomGenerator.GenerateCall.Receives.Paths).To(Equal([]string{workingDir}))
+		})
+
+		context("when workspaces are disabled", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), nil, os.ModePerm)).To(Succeed())
+				configParser.ParseCall.Returns.BuildConfiguration.DisableWorkspace = true
+			})
+
+			it("generates the SBOM from the go.mod file", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.ExecuteCall.Receives.Config).To(Equal(gomodvendor.BuildConfiguration{DisableWorkspace: true}))
+				Expect(sbomGenerator.GenerateCall.Receives.Paths).To(Equal([]string{filepath.Join(workingDir, "go.mod")}))
+			})
+		})
+	})
+
+	context("when the build process should not run", func() {
+		it.Before(func() {
+			buildProcess.ShouldRunCall.Returns.Ok = false
+			buildProcess.ShouldRunCall.Returns.Reason = "module graph is empty"
+		})
+
+		it("skips vendoring and SBOM generation but keeps the module cache", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+			Expect(result.Layers[0].Name).To(Equal("mod-cache"))
+			Expect(filepath.Join(layersDir, "mod-cache", "cache")).To(BeAnExistingFile())
+
+			Expect(buildProcess.ExecuteCall.CallCount).To(Equal(0))
+			Expect(buildProcess.ModCacheSettingsCall.CallCount).To(Equal(0))
+			Expect(sbomGenerator.GenerateCall.CallCount).To(Equal(0))
+
+			Expect(logs.String()).To(ContainSubstring("Skipping build process: module graph is empty"))
+		})
+	})
+
+	context("when modules are replaced by local directories", func() {
+		var replacements []gomodvendor.LocalReplacement
+
+		it.Before(func() {
+			replacements = []gomodvendor.LocalReplacement{
+				{
+					Old: gomodvendor.ModuleVersion{Path: "example.com/shared"},
+					New: "./libs/shared",
+					Dir: "libs/shared",
+				},
+			}
+
+			buildProcess.LocalReplacementsCall.Returns.LocalReplacementSlice = replacements
+		})
+
+		it("lists them in the logs and in the SBOM", func() {
+			_, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildProcess.LocalReplacementsCall.Receives.RootDir).To(Equal(workingDir))
+			Expect(buildProcess.LocalReplacementsCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(sbomGenerator.GenerateCall.Receives.LocalModules).To(Equal(replacements))
+
+			Expect(logs.String()).To(ContainSubstring("Using local replacements"))
+			Expect(logs.String()).To(ContainSubstring("example.com/shared => ./libs/shared"))
+		})
+
+		context("when the module is already vendored", func() {
+			it.Before(func() {
+				buildProcess.ShouldRunCall.Returns.Ok = false
+				buildProcess.ShouldRunCall.Returns.Reason = "modules are already vendored"
+			})
+
+			it("does not check them", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.LocalReplacementsCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when a replacement is invalid", func() {
+			it.Before(func() {
+				buildProcess.LocalReplacementsCall.Returns.Error = errors.New("go.mod:5: replace example.com/shared => ../shared: '../shared' does not exist")
+			})
+
+			it("returns an error before running the build process", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("go.mod:5: replace example.com/shared => ../shared: '../shared' does not exist"))
+
+				Expect(buildProcess.ExecuteCall.CallCount).To(Equal(0))
+			})
+		})
+	})
+
+	context("when the vendor layer is enabled", func() {
+		it.Before(func() {
+			configParser.ParseCall.Returns.BuildConfiguration.VendorLayer = true
+			buildProcess.VendorDigestCall.Returns.Ok = true
+		})
+
+		it("vendors into a build-only layer", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildProcess.ExecuteCall.Receives.VendorPath).To(Equal(filepath.Join(layersDir, "vendor", "vendor")))
+			Expect(buildProcess.ExecuteCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(buildProcess.VendorDigestCall.CallCount).To(Equal(0))
+
+			Expect(result.Layers).To(HaveLen(2))
+			Expect(result.Layers[0].Name).To(Equal("mod-cache"))
+
+			layer := result.Layers[1]
+			Expect(layer.Name).To(Equal("vendor"))
+			Expect(layer.Path).To(Equal(filepath.Join(layersDir, "vendor")))
+			Expect(layer.Build).To(BeTrue())
+			Expect(layer.Launch).To(BeFalse())
+			Expect(layer.Cache).To(BeFalse())
+			Expect(layer.BuildEnv).To(Equal(packit.Environment{
+				"GOFLAGS.append":              "-mod=vendor",
+				"GOFLAGS.delim":               " ",
+				"GO_MOD_VENDOR_ROOT.override": filepath.Join(layersDir, "vendor"),
+			}))
+
+			Expect(logs.String()).To(ContainSubstring("Configuring build environment"))
+			Expect(logs.String()).To(ContainSubstring(fmt.Sprintf(`GO_MOD_VENDOR_ROOT -> "%s"`, filepath.Join(layersDir, "vendor"))))
+		})
+
+		context("when the go command writes a vendor directory", func() {
+			var goCache string
+
+			it.Before(func() {
+				var err error
+				goCache, err = os.MkdirTemp("", "go-cache")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n\nrequire example.com/dependency v1.0.0\n"), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "main.go"), []byte("package main\n\nimport \"example.com/dependency\"\n\nfunc main() { dependency.Run() }\n"), 0644)).To(Succeed())
+
+				buildProcess.ExecuteCall.Stub = func(path, vendorPath, workingDir string, config gomodvendor.BuildConfiguration) error {
+					err := os.MkdirAll(filepath.Join(vendorPath, "example.com", "dependency"), os.ModePerm)
+					if err != nil {
+						return err
+					}
+
+					err = os.WriteFile(filepath.Join(vendorPath, "modules.txt"), []byte("# example.com/dependency v1.0.0\n## explicit\nexample.com/dependency\n"), 0644)
+					if err != nil {
+						return err
+					}
+
+					return os.WriteFile(filepath.Join(vendorPath, "example.com", "dependency", "dependency.go"), []byte("package dependency\n\nfunc Run() {}\n"), 0644)
+				}
+			})
+
+			it.After(func() {
+				Expect(os.RemoveAll(goCache)).To(Succeed())
+			})
+
+			it("links it into the module so that a later go build finds the vendored packages", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				link, err := os.Readlink(filepath.Join(workingDir, "vendor"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(link).To(Equal(filepath.Join(layersDir, "vendor", "vendor")))
+
+				if _, err := exec.LookPath("go"); err != nil {
+					t.Skip("the go command is not installed")
+				}
+
+				goBuild := exec.Command("go", "build", "-o", os.DevNull, ".")
+				goBuild.Dir = workingDir
+				goBuild.Env = append(os.Environ(),
+					"GOFLAGS="+result.Layers[1].BuildEnv["GOFLAGS.append"],
+					"GOCACHE="+goCache,
+					"GOPROXY=off",
+					"GOTOOLCHAIN=local",
+					"GOWORK=off",
+				)
+				output, err := goBuild.CombinedOutput()
+				Expect(err).NotTo(HaveOccurred(), string(output))
+			})
+
+			context("when the module has a link left by a previous build", func() {
+				it.Before(func() {
+					Expect(os.Symlink(filepath.Join(layersDir, "previous"), filepath.Join(workingDir, "vendor"))).To(Succeed())
+				})
+
+				it("replaces the link", func() {
+					_, err := build(packit.BuildContext{
+						Layers:     packit.Layers{Path: layersDir},
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					link, err := os.Readlink(filepath.Join(workingDir, "vendor"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(link).To(Equal(filepath.Join(layersDir, "vendor", "vendor")))
+				})
+			})
+		})
+
+		context("when the app has a committed vendor directory", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "vendor"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "modules.txt"), []byte("# committed\n"), 0644)).To(Succeed())
+			})
+
+			it("fails without vendoring or touching the vendor directory", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(fmt.Sprintf("BP_GO_MOD_VENDOR_LAYER cannot replace the vendor directory committed to the app at '%s': remove it from the app or unset BP_GO_MOD_VENDOR_LAYER", filepath.Join(workingDir, "vendor"))))
+
+				Expect(buildProcess.ExecuteCall.CallCount).To(Equal(0))
+
+				info, err := os.Lstat(filepath.Join(workingDir, "vendor"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.IsDir()).To(BeTrue())
+
+				content, err := os.ReadFile(filepath.Join(workingDir, "vendor", "modules.txt"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("# committed\n"))
+			})
+		})
+
+		context("when the layer holds a previous vendor directory", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(layersDir, "vendor", "vendor"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "vendor", "vendor", "modules.txt"), nil, os.ModePerm)).To(Succeed())
+			})
+
+			it("removes it before vendoring", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "vendor", "vendor")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("when several modules are configured", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+				configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{".", "tools"}
+
+				buildProcess.ExecuteCall.Stub = func(path, vendorPath, workingDir string, config gomodvendor.BuildConfiguration) error {
+					executions = append(executions, [2]string{vendorPath, workingDir})
+					return nil
+				}
+			})
+
+			it("mirrors the working directory in the layer", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(Equal([][2]string{
+					{filepath.Join(layersDir, "vendor", "vendor"), workingDir},
+					{filepath.Join(layersDir, "vendor", "tools", "vendor"), filepath.Join(workingDir, "tools")},
+				}))
+			})
+		})
+
+		context("when the vendor mode is download", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "download"
+			})
+
+			it("does not create the layer", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(1))
+				Expect(result.Layers[0].Name).To(Equal("mod-cache"))
+			})
+		})
+	})
+
+	context("when the module declares tools", func() {
+		it.Before(func() {
+			buildProcess.ToolModulesCall.Returns.StringSlice = []string{"github.com/some/tool"}
+		})
+
+		it("marks the modules that provide them in the SBOM", func() {
+			_, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildProcess.ToolModulesCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+			Expect(buildProcess.ToolModulesCall.Receives.VendorPath).To(Equal(filepath.Join(workingDir, "vendor")))
+			Expect(buildProcess.ToolModulesCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(sbomGenerator.GenerateCall.Receives.ToolModules).To(Equal([]string{"github.com/some/tool"}))
+		})
+
+		context("when modules are downloaded", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "download"
+			})
+
+			it("reads them from the module cache", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.ToolModulesCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+				Expect(buildProcess.ToolModulesCall.Receives.VendorPath).To(BeEmpty())
+			})
+		})
+
+		context("when the tool modules cannot be determined", func() {
+			it.Before(func() {
+				buildProcess.ToolModulesCall.Returns.Error = errors.New("failed to parse go.mod")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("failed to parse go.mod"))
+			})
+		})
+	})
+
+	context("when the vendor directory has a digest", func() {
+		var digest gomodvendor.VendorDigest
+
+		it.Before(func() {
+			digest = gomodvendor.VendorDigest{
+				Inputs:  "h1:inputs=",
+				Digest:  "h1:vendor=",
+				Modules: map[string]string{"github.com/some/dependency": "h1:module="},
+			}
+
+			buildProcess.VendorDigestCall.Returns.Digest = digest
+			buildProcess.VendorDigestCall.Returns.Ok = true
+		})
+
+		it("records the digest after vendoring", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildProcess.VendorDigestCall.Receives.WorkingDir).To(Equal(workingDir))
+
+			Expect(result.Layers).To(HaveLen(1))
+			Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("vendor-digests", map[string]gomodvendor.VendorDigest{".": digest}))
+
+			Expect(logs.String()).To(ContainSubstring("Vendor digest: h1:vendor="))
+		})
+
+		context("when the vendor mode is download", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "download"
+			})
+
+			it("does not record a digest", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.VendorDigestCall.CallCount).To(Equal(0))
+				Expect(result.Layers[0].Metadata).NotTo(HaveKey("vendor-digests"))
+			})
+		})
+
+		context("when the module is already vendored", func() {
+			it.Before(func() {
+				buildProcess.ShouldRunCall.Returns.Ok = false
+				buildProcess.ShouldRunCall.Returns.Reason = "modules are already vendored"
+			})
+
+			it("records the digest of the existing vendor directory", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.ExecuteCall.CallCount).To(Equal(0))
+
+				Expect(result.Layers).To(HaveLen(1))
+				Expect(result.Layers[0].Name).To(Equal("mod-cache"))
+				Expect(result.Layers[0].Metadata).To(Equal(map[string]interface{}{
+					"vendor-digests": map[string]gomodvendor.VendorDigest{".": digest},
+				}))
+			})
+
+			context("when a previous build recorded a digest", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache.toml"), []byte(`
+[metadata]
+  [metadata.vendor-digests]
+    [metadata.vendor-digests."."]
+      inputs = "h1:inputs="
+      digest = "h1:previous="
+      [metadata.vendor-digests.".".modules]
+        "github.com/some/dependency" = "h1:previous-module="
+`), os.ModePerm)).To(Succeed())
+				})
+
+				it("warns that the vendor directory was edited and keeps the previous digest", func() {
+					result, err := build(packit.BuildContext{
+						Layers:     packit.Layers{Path: layersDir},
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Layers).To(HaveLen(1))
+					Expect(result.Layers[0].Metadata["vendor-digests"]).To(Equal(map[string]gomodvendor.VendorDigest{
+						".": {
+							Inputs:  "h1:inputs=",
+							Digest:  "h1:previous=",
+							Modules: map[string]string{"github.com/some/dependency": "h1:previous-module="},
+						},
+					}))
+
+					Expect(logs.String()).To(ContainSubstring("WARNING: the vendor directory was edited since it was generated from the current go.mod and go.sum:"))
+					Expect(logs.String()).To(ContainSubstring("github.com/some/dependency"))
+				})
+
+				context("when BP_GO_MOD_VENDOR_EDITS is fail", func() {
+					it.Before(func() {
+						configParser.ParseCall.Returns.BuildConfiguration.VendorEdits = "fail"
+					})
+
+					it("returns an error", func() {
+						_, err := build(packit.BuildContext{
+							Layers:     packit.Layers{Path: layersDir},
+							WorkingDir: workingDir,
+						})
+						Expect(err).To(MatchError("the vendor directory was edited since it was generated from the current go.mod and go.sum:\n  github.com/some/dependency\n\nrun 'go mod vendor' and commit the result, or set BP_GO_MOD_VENDOR_EDITS=warn to only log a warning"))
+					})
+				})
+
+				context("when go.mod or go.sum changed", func() {
+					it.Before(func() {
+						digest.Inputs = "h1:other-inputs="
+						buildProcess.VendorDigestCall.Returns.Digest = digest
+					})
+
+					it("records the new digest without a warning", func() {
+						result, err := build(packit.BuildContext{
+							Layers:     packit.Layers{Path: layersDir},
+							WorkingDir: workingDir,
+						})
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(result.Layers[0].Metadata["vendor-digests"]).To(Equal(map[string]gomodvendor.VendorDigest{".": digest}))
+						Expect(logs.String()).NotTo(ContainSubstring("WARNING"))
+					})
+				})
+			})
+
+			context("when the digest cannot be computed", func() {
+				it.Before(func() {
+					buildProcess.VendorDigestCall.Returns.Err = errors.New("failed to compute the vendor digest")
+				})
+
+				it("returns an error", func() {
+					_, err := build(packit.BuildContext{
+						Layers:     packit.Layers{Path: layersDir},
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError("failed to compute the vendor digest"))
+				})
+			})
+		})
+	})
+
+	context("when the vendor tree can be cached", func() {
+		var snapshotDir string
+
+		it.Before(func() {
+			buildProcess.VendorKeyCall.Returns.String = "some-key"
+			buildProcess.ExecuteCall.Stub = func(path, vendorPath, workingDir string, config gomodvendor.BuildConfiguration) error {
+				Expect(os.MkdirAll(filepath.Join(vendorPath, "github.com", "some", "dependency"), os.ModePerm)).To(Succeed())
+				return os.WriteFile(filepath.Join(vendorPath, "modules.txt"), []byte("# github.com/some/dependency v1.2.3\n"), 0600)
+			}
+
+			snapshotDir = filepath.Join(layersDir, "vendor-cache", fmt.Sprintf("%x", sha256.Sum256([]byte(".")))[:16])
+		})
+
+		it("saves a snapshot of the vendor tree", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildProcess.ExecuteCall.CallCount).To(Equal(1))
+			Expect(buildProcess.VendorKeyCall.Receives.WorkingDir).To(Equal(workingDir))
+
+			Expect(result.Layers).To(HaveLen(2))
+			layer := result.Layers[1]
+			Expect(layer.Name).To(Equal("vendor-cache"))
+			Expect(layer.Cache).To(BeTrue())
+			Expect(layer.Build).To(BeFalse())
+			Expect(layer.Launch).To(BeFalse())
+			Expect(layer.Metadata).To(Equal(map[string]interface{}{
+				"vendor-snapshots": map[string]string{".": "some-key"},
+			}))
+
+			content, err := os.ReadFile(filepath.Join(snapshotDir, "modules.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("# github.com/some/dependency v1.2.3\n"))
+			Expect(filepath.Join(snapshotDir, "github.com", "some", "dependency")).To(BeADirectory())
+		})
+
+		context("when a snapshot matches the key", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layersDir, "vendor-cache.toml"), []byte(`[metadata.vendor-snapshots]
+  "." = "some-key"
+`), 0600)).To(Succeed())
+
+				Expect(os.MkdirAll(filepath.Join(snapshotDir, "github.com", "some", "dependency"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(snapshotDir, "modules.txt"), []byte("# github.com/some/dependency v1.2.3\n"), 0600)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(layersDir, "vendor-cache", "stale"), os.ModePerm)).To(Succeed())
+			})
+
+			it("restores the vendor tree without running the go command", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.ExecuteCall.CallCount).To(Equal(0))
+
+				content, err := os.ReadFile(filepath.Join(workingDir, "vendor", "modules.txt"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("# github.com/some/dependency v1.2.3\n"))
+				Expect(filepath.Join(workingDir, "vendor", "github.com", "some", "dependency")).To(BeADirectory())
+
+				Expect(result.Layers).To(HaveLen(2))
+				Expect(result.Layers[1].Name).To(Equal("vendor-cache"))
+				Expect(filepath.Join(layersDir, "vendor-cache", "stale")).NotTo(BeAnExistingFile())
+
+				Expect(logs.String()).To(ContainSubstring("Reusing cached vendor tree"))
+			})
+
+			context("when the key has changed", func() {
+				it.Before(func() {
+					buildProcess.VendorKeyCall.Returns.String = "other-key"
+				})
+
+				it("vendors again and replaces the snapshot", func() {
+					result, err := build(packit.BuildContext{
+						Layers:     packit.Layers{Path: layersDir},
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(buildProcess.ExecuteCall.CallCount).To(Equal(1))
+					Expect(result.Layers[1].Metadata).To(Equal(map[string]interface{}{
+						"vendor-snapshots": map[string]string{".": "other-key"},
+					}))
+
+					Expect(logs.String()).NotTo(ContainSubstring("Reusing cached vendor tree"))
+				})
+			})
+
+			context("when the build process should not run", func() {
+				it.Before(func() {
+					buildProcess.ShouldRunCall.Returns.Ok = false
+				})
+
+				it("keeps the snapshot", func() {
+					result, err := build(packit.BuildContext{
+						Layers:     packit.Layers{Path: layersDir},
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Layers).To(HaveLen(2))
+					Expect(result.Layers[1].Name).To(Equal("vendor-cache"))
+					Expect(result.Layers[1].Cache).To(BeTrue())
+					Expect(result.Layers[1].Metadata).To(Equal(map[string]interface{}{
+						"vendor-snapshots": map[string]string{".": "some-key"},
+					}))
+					Expect(filepath.Join(snapshotDir, "modules.txt")).To(BeARegularFile())
+				})
+			})
+
+			context("when the vendor mode is none", func() {
+				it.Before(func() {
+					configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "none"
+				})
+
+				it("keeps the snapshot", func() {
+					result, err := build(packit.BuildContext{
+						Layers:     packit.Layers{Path: layersDir},
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Layers).To(HaveLen(2))
+					Expect(result.Layers[1].Name).To(Equal("vendor-cache"))
+				})
+			})
+
+			context("when the vendor mode is download", func() {
+				it.Before(func() {
+					configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "download"
+				})
+
+				it("keeps the snapshot", func() {
+					result, err := build(packit.BuildContext{
+						Layers:     packit.Layers{Path: layersDir},
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Layers).To(HaveLen(2))
+					Expect(result.Layers[1].Name).To(Equal("vendor-cache"))
+					Expect(result.Layers[1].Metadata).To(Equal(map[string]interface{}{
+						"vendor-snapshots": map[string]string{".": "some-key"},
+					}))
+				})
+			})
+
+			context("when the module is no longer configured", func() {
+				it.Before(func() {
+					Expect(os.MkdirAll(filepath.Join(workingDir, "api"), os.ModePerm)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(workingDir, "api", "go.mod"), nil, os.ModePerm)).To(Succeed())
+					configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{"api"}
+					buildProcess.ShouldRunCall.Returns.Ok = false
+				})
+
+				it("drops the snapshot", func() {
+					result, err := build(packit.BuildContext{
+						Layers:     packit.Layers{Path: layersDir},
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					for _, layer := range result.Layers {
+						Expect(layer.Name).NotTo(Equal("vendor-cache"))
+					}
+				})
+			})
+		})
+
+		context("when the module is replaced by a local directory", func() {
+			it.Before(func() {
+				buildProcess.LocalReplacementsCall.Returns.LocalReplacementSlice = []gomodvendor.LocalReplacement{
+					{
+						Old: gomodvendor.ModuleVersion{Path: "example.com/local"},
+						New: "./libs/local",
+						Dir: "libs/local",
+					},
+				}
+			})
+
+			it("does not cache the vendor tree", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.VendorKeyCall.CallCount).To(Equal(0))
+				Expect(result.Layers).To(HaveLen(1))
+			})
+		})
+	})
+
+	context("when the module cache is pruned", func() {
+		it("prunes it with the go.sum file of the module", func() {
+			_, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildProcess.PruneCacheCall.CallCount).To(Equal(1))
+			Expect(buildProcess.PruneCacheCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+			Expect(buildProcess.PruneCacheCall.Receives.WorkingDirs).To(Equal([]string{workingDir}))
+		})
+
+		context("when several modules share the module cache", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+				configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{".", "tools"}
+				configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "download"
+			})
+
+			it("prunes it once with the go.sum files of every module", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.PruneCacheCall.CallCount).To(Equal(1))
+				Expect(buildProcess.PruneCacheCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+				Expect(buildProcess.PruneCacheCall.Receives.WorkingDirs).To(Equal([]string{workingDir, filepath.Join(workingDir, "tools")}))
+			})
+		})
+
+		context("when the module cache cannot be pruned", func() {
+			it.Before(func() {
+				buildProcess.PruneCacheCall.Returns.Error = errors.New("failed to prune the module cache")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("failed to prune the module cache"))
+			})
+		})
+	})
+
+	context("when BP_GO_MOD_CACHE_MAX_SIZE is set", func() {
+		var (
+			now        time.Time
+			namespace  string
+			toolsCache string
+			write      func(path, content string)
+		)
+
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+			configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{".", "tools"}
+			configParser.ParseCall.Returns.BuildConfiguration.CacheMaxSize = 20
+
+			buildProcess.PruneCacheCall.Returns.StringSlice = []string{"github.com/some/dependency@v1.2.3"}
+
+			buildProcess.ShouldRunCall.Stub = func(workingDir string, config gomodvendor.BuildConfiguration) (bool, string, error) {
+				return filepath.Base(workingDir) != "tools", "modules are already vendored", nil
+			}
+
+			namespace = fmt.Sprintf("%x", sha256.Sum256([]byte(".")))[:16]
+			toolsCache = fmt.Sprintf("%x", sha256.Sum256([]byte("tools")))[:16]
+
+			write = func(path, content string) {
+				Expect(os.MkdirAll(filepath.Dir(filepath.Join(layersDir, "mod-cache", path)), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache", path), []byte(content), 0444)).To(Succeed())
+			}
+
+			write(namespace+"/github.com/some/dependency@v1.2.3/dependency.go", "0123456789")
+			write(toolsCache+"/github.com/old/a@v1.0.0/a.go", "0123456789")
+			write(toolsCache+"/github.com/old/b@v1.0.0/b.go", "0123456789")
+
+			Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache.toml"), []byte(fmt.Sprintf(`[metadata]
+  [metadata.last-used]
+    "%s/github.com/old/a@v1.0.0" = "2026-01-01T00:00:00Z"
+`, toolsCache)), 0600)).To(Succeed())
+
+			now = time.Now()
+			clock = chronos.NewClock(func() time.Time {
+				return now
+			})
+
+			build = gomodvendor.Build(
+				configParser,
+				buildProcess,
+				scribe.NewEmitter(logs).WithLevel("DEBUG"),
+				clock,
+				sbomGenerator,
+				bindingResolver,
+			)
+		})
+
+		it("evicts the least recently used module versions and records when the others were used", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layersDir, "mod-cache", toolsCache, "github.com", "old", "b@v1.0.0")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(layersDir, "mod-cache", toolsCache, "github.com", "old", "a@v1.0.0", "a.go")).To(BeARegularFile())
+			Expect(filepath.Join(layersDir, "mod-cache", namespace, "github.com", "some", "dependency@v1.2.3", "dependency.go")).To(BeARegularFile())
+
+			Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("last-used", map[string]string{
+				namespace + "/github.com/some/dependency@v1.2.3": now.Format(time.RFC3339),
+				toolsCache + "/github.com/old/a@v1.0.0":          "2026-01-01T00:00:00Z",
+			}))
+
+			Expect(logs.String()).To(ContainSubstring("Evicting module versions to fit the module cache in 20 B"))
+			Expect(logs.String()).To(ContainSubstring(fmt.Sprintf("%s/github.com/old/b@v1.0.0 (10 B, last used never)", toolsCache)))
+		})
+
+		context("when a cache used by the build holds versions that the build does not resolve", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.BuildConfiguration.CacheMaxSize = 10
+
+				write(namespace+"/github.com/some/other@v0.1.0/other.go", "0123456789")
+
+				Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache.toml"), []byte(fmt.Sprintf(`[metadata]
+  [metadata.last-used]
+    "%s/github.com/old/a@v1.0.0" = "2026-01-01T00:00:00Z"
+    "%s/github.com/some/other@v0.1.0" = "2026-02-01T00:00:00Z"
+`, toolsCache, namespace)), 0600)).To(Succeed())
+			})
+
+			it("keeps the time they were last used and evicts them before the resolved versions", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "mod-cache", namespace, "github.com", "some", "other@v0.1.0")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "mod-cache", namespace, "github.com", "some", "dependency@v1.2.3", "dependency.go")).To(BeARegularFile())
+
+				Expect(result.Layers[0].Metadata).To(HaveKeyWithValue("last-used", map[string]string{
+					namespace + "/github.com/some/dependency@v1.2.3": now.Format(time.RFC3339),
+				}))
+
+				Expect(logs.String()).To(ContainSubstring(fmt.Sprintf("%s/github.com/some/other@v0.1.0 (10 B, last used 2026-02-01T00:00:00Z)", namespace)))
+			})
+		})
+
+		context("when the vendor mode is download", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = nil
+				configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "download"
+				configParser.ParseCall.Returns.BuildConfiguration.CacheMaxSize = 5
+				buildProcess.ShouldRunCall.Stub = nil
+
+				Expect(os.Remove(filepath.Join(layersDir, "mod-cache", "cache"))).To(Succeed())
+				write("github.com/some/dependency@v1.2.3/dependency.go", "0123456789")
+			})
+
+			it("does not evict the modules of the build and logs a warning", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "mod-cache", "github.com", "some", "dependency@v1.2.3", "dependency.go")).To(BeARegularFile())
+				Expect(logs.String()).To(ContainSubstring("WARNING: the module cache holds 10 B, more than BP_GO_MOD_CACHE_MAX_SIZE, as the modules downloaded for this build cannot be evicted"))
+			})
+		})
+	})
+
+	context("when the module cache settings are known", func() {
+		it.Before(func() {
+			buildProcess.ModCacheSettingsCall.Returns.ModCacheSettings = gomodvendor.ModCacheSettings{
+				GoVersion: "go1.23.4",
+				GoProxy:   "https://proxy.golang.org,direct",
+				GoPrivate: "example.com/private",
+				GoFlags:   "-mod=mod",
+			}
+		})
+
+		it("records them in the layer metadata", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildProcess.ModCacheSettingsCall.Receives.WorkingDir).To(Equal(workingDir))
+
+			Expect(result.Layers).To(HaveLen(1))
+			Expect(result.Layers[0].Metadata).To(Equal(map[string]interface{}{
+				"go-version":        "go1.23.4",
+				"goproxy":           "https://proxy.golang.org,direct",
+				"goprivate":         "example.com/private",
+				"goflags":           "-mod=mod",
+				"buildpack-version": "some-version",
+			}))
+		})
+
+		context("when the layer was written with the same settings", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache.toml"), []byte(`[metadata]
+  go-version = "go1.23.4"
+  goproxy = "https://proxy.golang.org,direct"
+  goprivate = "example.com/private"
+  goflags = "-mod=mod"
+  buildpack-version = "some-version"
+`), 0600)).To(Succeed())
+			})
+
+			it("reuses the module cache", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers).To(HaveLen(1))
+				Expect(filepath.Join(layersDir, "mod-cache", "cache")).To(BeAnExistingFile())
+				Expect(logs.String()).NotTo(ContainSubstring("Resetting the module cache"))
+			})
+		})
+
+		context("when the layer was written with other settings", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache.toml"), []byte(`[metadata]
+  go-version = "go1.22.5"
+  goproxy = "https://proxy.golang.org,direct"
+  goprivate = "example.com/private"
+  goflags = "-mod=mod"
+  buildpack-version = "some-version"
+`), 0600)).To(Succeed())
+
+				// The go command makes the directories of extracted modules
+				// read-only.
+				Expect(os.MkdirAll(filepath.Join(layersDir, "mod-cache", "github.com", "some", "dependency@v1.2.3"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache", "github.com", "some", "dependency@v1.2.3", "go.mod"), nil, 0444)).To(Succeed())
+				Expect(os.Chmod(filepath.Join(layersDir, "mod-cache", "github.com", "some", "dependency@v1.2.3"), 0555)).To(Succeed())
+			})
+
+			it("resets the module cache and logs the reason", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layersDir, "mod-cache", "cache")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "mod-cache", "github.com")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(layersDir, "mod-cache")).To(BeADirectory())
+				Expect(result.Layers).To(BeEmpty())
+
+				Expect(buildProcess.ExecuteCall.Receives.Path).To(Equal(filepath.Join(layersDir, "mod-cache")))
+				Expect(logs.String()).To(ContainSubstring("Resetting the module cache: the Go version changed from 'go1.22.5' to 'go1.23.4'"))
+			})
+		})
+
+		context("when several modules are vendored", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{".", "tools"}
+				Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, 0600)).To(Succeed())
+
+				buildProcess.ModCacheSettingsCall.Stub = func(dir string, config gomodvendor.BuildConfiguration) (gomodvendor.ModCacheSettings, error) {
+					settings := gomodvendor.ModCacheSettings{
+						GoVersion: "go1.23.4",
+						GoProxy:   "https://proxy.golang.org,direct",
+					}
+
+					if dir == filepath.Join(workingDir, "tools") {
+						settings.GoVersion = "go1.24.0"
+					}
+
+					return settings, nil
+				}
+			})
+
+			it("records the settings of every module", func() {
+				result, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					BuildpackInfo: packit.BuildpackInfo{
+						Name:    "Some Buildpack",
+						Version: "some-version",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildProcess.ModCacheSettingsCall.CallCount).To(Equal(2))
+
+				Expect(result.Layers[0].Metadata).To(Equal(map[string]interface{}{
+					"go-version":        "go1.23.4; go1.24.0",
+					"goproxy":           "https://proxy.golang.org,direct",
+					"goprivate":         "",
+					"goflags":           "",
+					"buildpack-version": "some-version",
+				}))
+			})
+		})
+
+		context("when the settings cannot be read", func() {
+			it.Before(func() {
+				buildProcess.ModCacheSettingsCall.Returns.Error = errors.New("failed to read the go environment")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("failed to read the go environment"))
+			})
+		})
+	})
+
+	context("when go-mod-cache bindings are provided", func() {
+		var platformDir string
+
+		it.Before(func() {
+			var err error
+			platformDir, err = os.MkdirTemp("", "platform")
+			Expect(err).NotTo(HaveOccurred())
+
+			bindingPath := filepath.Join(platformDir, "bindings", "some-binding")
+			Expect(os.MkdirAll(filepath.Join(bindingPath, "github.com", "some", "dependency", "@v"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(bindingPath, "type"), []byte("go-mod-cache"), 0600)).To(Succeed())
+
+			bindingResolver.ResolveCall.Returns.BindingSlice = []servicebindings.Binding{
+				{
+					Name: "some-binding",
+					Path: bindingPath,
+					Type: "go-mod-cache",
+					Entries: map[string]*servicebindings.Entry{
+						"type":       servicebindings.NewEntry(filepath.Join(bindingPath, "type")),
+						"github.com": servicebindings.NewEntry(filepath.Join(bindingPath, "github.com")),
+					},
+				},
+			}
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(platformDir)).To(Succeed())
+		})
+
+		it("downloads modules from the bindings", func() {
+			_, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				Platform:   packit.Platform{Path: platformDir},
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(bindingResolver.ResolveCall.Receives.Typ).To(Equal("go-mod-cache"))
+			Expect(bindingResolver.ResolveCall.Receives.Provider).To(BeEmpty())
+			Expect(bindingResolver.ResolveCall.Receives.PlatformDir).To(Equal(platformDir))
+
+			Expect(buildProcess.ExecuteCall.Receives.Config.ModuleProxies).To(Equal([]string{
+				filepath.Join(platformDir, "bindings", "some-binding"),
+			}))
+			Expect(buildProcess.ModCacheSettingsCall.Receives.Config.ModuleProxies).To(Equal([]string{
+				filepath.Join(platformDir, "bindings", "some-binding"),
+			}))
+
+			Expect(logs.String()).To(ContainSubstring("Using modules from the go-mod-cache bindings"))
+			Expect(logs.String()).To(ContainSubstring("some-binding"))
+		})
+
+		context("when the build process should not run", func() {
+			it.Before(func() {
+				buildProcess.ShouldRunCall.Returns.Ok = false
+			})
+
+			it("does not resolve the bindings", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					Platform:   packit.Platform{Path: platformDir},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bindingResolver.ResolveCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when a binding does not provide modules", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(platformDir, "bindings", "some-binding", "github.com", "some", "dependency", "@v"))).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+					Platform:   packit.Platform{Path: platformDir},
+				})
+				Expect(err).To(MatchError(ContainSubstring("the 'some-binding' binding does not contain modules")))
+			})
+		})
+	})
+
+	context("when the mod cache layer does not exist", func() {
+		it.Before(func() {
+			err := os.RemoveAll(filepath.Join(layersDir, "mod-cache"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("does not include the module cache layer in the build result", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(BeEmpty())
+		})
+	})
+
+	context("when the mod cache layer is empty", func() {
+		it.Before(func() {
+			err := os.RemoveAll(filepath.Join(layersDir, "mod-cache", "cache"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("does not include the module cache layer in the build result", func() {
+			result, err := build(packit.BuildContext{
+				Layers:     packit.Layers{Path: layersDir},
+				WorkingDir: workingDir,
+				BuildpackInfo: packit.BuildpackInfo{
+					Name:    "Some Buildpack",
+					Version: "some-version",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(BeEmpty())
+		})
+	})
+
+	context("failure cases", func() {
+		context("the build configuration cannot be parsed", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.Error = errors.New("failed to parse configuration")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("failed to parse configuration"))
+			})
+		})
+
+		context("build process fails to check if it should run", func() {
+			it.Before(func() {
+				buildProcess.ShouldRunCall.Returns.Err = errors.New("build process failed to check")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("build process failed to check")))
+			})
+		})
+
+		context("modCacheLayer cannot be retrieved", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layersDir, "mod-cache.toml"), nil, 0000)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse layer content metadata")))
+			})
+		})
+
+		context("when the bindings cannot be resolved", func() {
+			it.Before(func() {
+				bindingResolver.ResolveCall.Returns.Error = errors.New("failed to resolve")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers:     packit.Layers{Path: layersDir},
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("failed to resolve the go-mod-cache bindings: failed to resolve"))
 			})
 		})
 
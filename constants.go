@@ -1,6 +1,14 @@
 package gomodvendor
 
 const (
-	GoLayerName   = "go"
-	GoModLocation = "go.mod"
+	GoLayerName    = "go"
+	GoModLocation  = "go.mod"
+	GoWorkLocation = "go.work"
+
+	// ImpliedGoVersion is the version the go command assumes for a go.mod
+	// file that has no go directive.
+	ImpliedGoVersion = "1.16"
+
+	ToolchainVersionSource  = "toolchain"
+	GoVersionOverrideSource = "BP_GO_VERSION"
 )
@@ -1,51 +1,312 @@
 package gomodvendor
 
 import (
+	"fmt"
 	"path/filepath"
 
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/fs"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
-//go:generate faux --interface VersionParser --output fakes/version_parser.go
-type VersionParser interface {
-	ParseVersion(path string) (version string, err error)
+//go:generate faux --interface ModuleParser --output fakes/module_parser.go
+type ModuleParser interface {
+	Parse(path string) (GoMod, error)
+	ParseWork(path string) (GoWork, error)
+	ParseModulesTxt(path string) (ModulesTxt, error)
+	ParseGoSum(path string) (GoSum, error)
 }
 
 type BuildPlanMetadata struct {
-	VersionSource string `toml:"version-source"`
-	Version       string `toml:"version"`
+	VersionSource string `toml:"version-source,omitempty"`
+	Version       string `toml:"version,omitempty"`
 	Build         bool   `toml:"build"`
 }
 
-func Detect(goModParser VersionParser) packit.DetectFunc {
+func Detect(configParser ConfigurationParser, goModParser ModuleParser, logs scribe.Emitter) packit.DetectFunc {
 	return func(context packit.DetectContext) (packit.DetectResult, error) {
-		goModFilepath := filepath.Join(context.WorkingDir, GoModLocation)
-		exists, err := fs.Exists(goModFilepath)
+		config, err := configParser.Parse(context.WorkingDir)
 		if err != nil {
 			return packit.DetectResult{}, err
 		}
-		if !exists {
-			return packit.DetectResult{}, packit.Fail.WithMessage("go.mod file is not present")
+
+		var requirements []goRequirement
+		var migrations []LegacyManifest
+		for _, path := range config.Modules() {
+			moduleDir := filepath.Join(context.WorkingDir, path)
+
+			goWorkFilepath, isWorkspace, err := findWorkspace(moduleDir, config)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			if isWorkspace {
+				vendor := config.VendorMode != VendorModeDownload && config.VendorMode != VendorModeNone
+				workspace, err := workspaceRequirements(goModParser, goWorkFilepath, vendor, logs)
+				if err != nil {
+					return packit.DetectResult{}, err
+				}
+
+				requirements = append(requirements, workspace...)
+				continue
+			}
+
+			goModFilepath := filepath.Join(moduleDir, GoModLocation)
+			exists, err := fs.Exists(goModFilepath)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+			if !exists {
+				legacy, found, err := FindLegacyManifest(moduleDir)
+				if err != nil {
+					return packit.DetectResult{}, err
+				}
+
+				if found {
+					if config.MigrateLegacy {
+						migrations = append(migrations, legacy)
+						continue
+					}
+
+					return packit.DetectResult{}, packit.Fail.WithMessage(
+						"go.mod file is not present, but %s from %s was found: migrate the app to Go modules, or set BP_GO_MOD_MIGRATE=true to generate go.mod from %s during the build",
+						legacy.Found, legacy.Tool, legacy.LockFile,
+					)
+				}
+
+				if path != "." {
+					return packit.DetectResult{}, packit.Fail.WithMessage("go.mod file is not present in '%s'", path)
+				}
+				return packit.DetectResult{}, packit.Fail.WithMessage("go.mod file is not present")
+			}
+
+			requirement, err := moduleRequirement(goModParser, goModFilepath, logs)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			requirements = append(requirements, requirement)
 		}
-		version, err := goModParser.ParseVersion(goModFilepath)
-		if err != nil {
-			return packit.DetectResult{}, err
+
+		metadata := BuildPlanMetadata{Build: true}
+
+		requirement, ok := highestRequirement(requirements)
+		switch {
+		case ok:
+			metadata.VersionSource = requirement.source
+			metadata.Version = requirement.Version()
+
+		// A migrated module has no go directive until go mod init writes one,
+		// so no version is requested and go-dist installs its default.
+		case len(migrations) > 0:
+
+		default:
+			return packit.DetectResult{}, fmt.Errorf("failed to determine the go version: no go or toolchain directive found")
+		}
+
+		if config.GoVersion != "" {
+			version, err := ParseVersionOverride(config.GoVersion)
+			if err != nil {
+				return packit.DetectResult{}, fmt.Errorf("failed to parse BP_GO_VERSION: %w", err)
+			}
+
+			for _, requirement := range requirements {
+				if requirement.hasMinimum && version.Compare(requirement.minimum) < 0 {
+					return packit.DetectResult{}, fmt.Errorf("BP_GO_VERSION '%s' is lower than go %s required by %s", config.GoVersion, requirement.minimum, requirement.requiredBy())
+				}
+			}
+
+			metadata.VersionSource = GoVersionOverrideSource
+			metadata.Version = version.Semver()
 		}
 
 		return packit.DetectResult{
 			Plan: packit.BuildPlan{
 				Requires: []packit.BuildPlanRequirement{
 					{
-						Name: GoLayerName,
-						Metadata: BuildPlanMetadata{
-							VersionSource: GoModLocation,
-							Build:         true,
-							Version:       version,
-						},
+						Name:     GoLayerName,
+						Metadata: metadata,
 					},
 				},
 			},
 		}, nil
 	}
 }
+
+// findWorkspace returns the path of the go.work file in the module
+// directory, unless workspaces have been disabled.
+func findWorkspace(moduleDir string, config BuildConfiguration) (string, bool, error) {
+	if config.DisableWorkspace {
+		return "", false, nil
+	}
+
+	path := filepath.Join(moduleDir, GoWorkLocation)
+	exists, err := fs.Exists(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	return path, exists, nil
+}
+
+// workVendorVersion is the first Go release with go work vendor.
+var workVendorVersion = GoVersion{Major: 1, Minor: 22}
+
+// workspaceRequirements returns the Go requirements of the go.work file and of
+// every module it uses. A workspace that is vendored also needs a Go with go
+// work vendor, whatever version go.work declares.
+func workspaceRequirements(goModParser ModuleParser, path string, vendor bool, logs scribe.Emitter) ([]goRequirement, error) {
+	goWork, err := goModParser.ParseWork(path)
+	if err != nil {
+		return nil, err
+	}
+
+	requirement, err := newGoRequirement(GoWorkLocation, goWork.Go, goWork.Toolchain)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := []goRequirement{requirement}
+	for _, use := range goWork.Use {
+		requirement, err := moduleRequirement(goModParser, filepath.Join(filepath.Dir(path), use.Path, GoModLocation), logs)
+		if err != nil {
+			return nil, err
+		}
+
+		requirements = append(requirements, requirement)
+	}
+
+	// The minimum only raises a declared version: a workspace without any go
+	// directive still fails detection.
+	_, declared := highestRequirement(requirements)
+	if vendor && declared {
+		requirements = append(requirements, goRequirement{
+			version:    workVendorVersion,
+			source:     GoWorkLocation,
+			ok:         true,
+			minimum:    workVendorVersion,
+			hasMinimum: true,
+			reason:     "'go work vendor'",
+		})
+	}
+
+	return requirements, nil
+}
+
+func moduleRequirement(goModParser ModuleParser, path string, logs scribe.Emitter) (goRequirement, error) {
+	goMod, err := goModParser.Parse(path)
+	if err != nil {
+		return goRequirement{}, err
+	}
+
+	// The go command treats a go.mod file without a go directive as if it
+	// declared go 1.16.
+	if goMod.Go.Value == "" {
+		logs.Process("WARNING: %s has no go directive, assuming go %s", path, ImpliedGoVersion)
+		logs.Break()
+
+		goMod.Go = Directive{Value: ImpliedGoVersion, Position: goMod.Module.Position}
+	}
+
+	return newGoRequirement(GoModLocation, goMod.Go, goMod.Toolchain)
+}
+
+// goRequirement is the version of Go needed by a go.mod or go.work file and
+// the directive it was taken from. A toolchain directive is requested exactly
+// so that the go command does not try to download a different toolchain
+// during the build.
+type goRequirement struct {
+	version GoVersion
+	exact   bool
+	source  string
+	ok      bool
+
+	// minimum is the version declared by the go directive, which is the
+	// oldest Go the module can be built with.
+	minimum         GoVersion
+	minimumPosition Position
+	hasMinimum      bool
+
+	// reason replaces the position of the directive when the minimum comes
+	// from a feature of the go command instead.
+	reason string
+}
+
+// requiredBy describes where the minimum version comes from.
+func (r goRequirement) requiredBy() string {
+	if r.reason != "" {
+		return r.reason
+	}
+
+	return r.minimumPosition.String()
+}
+
+func newGoRequirement(source string, goDirective, toolchainDirective Directive) (goRequirement, error) {
+	var requirement goRequirement
+	if goDirective.Value != "" {
+		version, err := ParseGoVersion(goDirective.Value)
+		if err != nil {
+			return goRequirement{}, fmt.Errorf("%s: %w", goDirective.Position, err)
+		}
+
+		requirement = goRequirement{
+			version:         version,
+			source:          source,
+			ok:              true,
+			minimum:         version,
+			minimumPosition: goDirective.Position,
+			hasMinimum:      true,
+		}
+	}
+
+	toolchain, ok, err := ParseToolchain(toolchainDirective.Value)
+	if err != nil {
+		return goRequirement{}, fmt.Errorf("%s: %w", toolchainDirective.Position, err)
+	}
+
+	// A toolchain older than the go directive is ignored, as the go command
+	// would ignore it too.
+	if ok && toolchain.Compare(requirement.version) >= 0 {
+		requirement.version = toolchain
+		requirement.exact = true
+		requirement.source = ToolchainVersionSource
+		requirement.ok = true
+	}
+
+	return requirement, nil
+}
+
+func (r goRequirement) Version() string {
+	if r.exact {
+		return r.version.Semver()
+	}
+
+	return r.version.Constraint()
+}
+
+// highestRequirement returns the requirement with the highest version. When
+// two requirements name the same version, an exact toolchain wins.
+func highestRequirement(requirements []goRequirement) (goRequirement, bool) {
+	var highest goRequirement
+	for _, requirement := range requirements {
+		if !requirement.ok {
+			continue
+		}
+
+		if !highest.ok {
+			highest = requirement
+			continue
+		}
+
+		switch requirement.version.Compare(highest.version) {
+		case 1:
+			highest = requirement
+		case 0:
+			if requirement.exact {
+				highest = requirement
+			}
+		}
+	}
+
+	return highest, highest.ok
+}
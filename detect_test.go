@@ -1,7 +1,9 @@
 package gomodvendor_test
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +11,7 @@ import (
 	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
 	"github.com/paketo-buildpacks/go-mod-vendor/fakes"
 	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/sclevine/spec"
 
 	. "github.com/onsi/gomega"
@@ -18,8 +21,10 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
 
-		workingDir  string
-		goModParser *fakes.VersionParser
+		workingDir   string
+		logs         *bytes.Buffer
+		configParser *fakes.ConfigurationParser
+		goModParser  *fakes.ModuleParser
 
 		detect        packit.DetectFunc
 		detectContext packit.DetectContext
@@ -30,11 +35,14 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		workingDir, err = os.MkdirTemp("", "working-dir")
 		Expect(err).NotTo(HaveOccurred())
 
-		goModParser = &fakes.VersionParser{}
+		configParser = &fakes.ConfigurationParser{}
+		goModParser = &fakes.ModuleParser{}
 
 		Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte{}, os.ModePerm)).To(Succeed())
 
-		detect = gomodvendor.Detect(goModParser)
+		logs = bytes.NewBuffer(nil)
+
+		detect = gomodvendor.Detect(configParser, goModParser, scribe.NewEmitter(logs))
 
 		detectContext = packit.DetectContext{WorkingDir: workingDir}
 	})
@@ -44,7 +52,9 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	it.Before(func() {
-		goModParser.ParseVersionCall.Returns.Version = ">= 1.15"
+		goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{
+			Go: gomodvendor.Directive{Value: "1.15"},
+		}
 	})
 
 	it("detects", func() {
@@ -63,6 +73,498 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				},
 			},
 		}))
+
+		Expect(goModParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.mod")))
+	})
+
+	context("when the go.mod has no go directive", func() {
+		it.Before(func() {
+			goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{
+				Module: gomodvendor.Module{Path: "example.com/app", Position: gomodvendor.Position{File: "go.mod", Line: 1}},
+			}
+		})
+
+		it("requests go 1.16 as the go command would and logs a warning", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+				VersionSource: "go.mod",
+				Version:       ">= 1.16",
+				Build:         true,
+			}))
+
+			Expect(logs.String()).To(ContainSubstring(fmt.Sprintf("WARNING: %s has no go directive, assuming go 1.16", filepath.Join(workingDir, "go.mod"))))
+		})
+
+		context("when the go.mod has a toolchain directive", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod.Toolchain = gomodvendor.Directive{Value: "go1.21.0"}
+			})
+
+			it("requests the toolchain version", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "toolchain",
+					Version:       "1.21.0",
+					Build:         true,
+				}))
+			})
+		})
+	})
+
+	context("when the go directive has a patch version", func() {
+		it.Before(func() {
+			goModParser.ParseCall.Returns.GoMod.Go = gomodvendor.Directive{Value: "1.21.3"}
+		})
+
+		it("requests at least that patch version", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+				VersionSource: "go.mod",
+				Version:       ">= 1.21.3",
+				Build:         true,
+			}))
+		})
+	})
+
+	context("when the go directive is a pre-release", func() {
+		it.Before(func() {
+			goModParser.ParseCall.Returns.GoMod.Go = gomodvendor.Directive{Value: "1.22rc1"}
+		})
+
+		it("requests at least that pre-release", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+				VersionSource: "go.mod",
+				Version:       ">= 1.22.0-rc.1",
+				Build:         true,
+			}))
+		})
+	})
+
+	context("when the go.mod has a toolchain directive", func() {
+		it.Before(func() {
+			goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{
+				Go:        gomodvendor.Directive{Value: "1.21"},
+				Toolchain: gomodvendor.Directive{Value: "go1.22.5"},
+			}
+		})
+
+		it("requests the exact toolchain version", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Requires: []packit.BuildPlanRequirement{
+					{
+						Name: "go",
+						Metadata: gomodvendor.BuildPlanMetadata{
+							VersionSource: "toolchain",
+							Version:       "1.22.5",
+							Build:         true,
+						},
+					},
+				},
+			}))
+		})
+
+		context("when the toolchain is a pre-release", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod.Toolchain = gomodvendor.Directive{Value: "go1.23rc1"}
+			})
+
+			it("requests the pre-release version", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "toolchain",
+					Version:       "1.23.0-rc.1",
+					Build:         true,
+				}))
+			})
+		})
+
+		context("when the toolchain has a custom suffix", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod.Toolchain = gomodvendor.Directive{Value: "go1.22.5-custom"}
+			})
+
+			it("requests the toolchain version without the suffix", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "toolchain",
+					Version:       "1.22.5",
+					Build:         true,
+				}))
+			})
+		})
+
+		context("when the toolchain is older than the go directive", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod.Toolchain = gomodvendor.Directive{Value: "go1.20.14"}
+			})
+
+			it("falls back to the go directive", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "go.mod",
+					Version:       ">= 1.21",
+					Build:         true,
+				}))
+			})
+		})
+
+		context("when the toolchain is default", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod.Toolchain = gomodvendor.Directive{Value: "default"}
+			})
+
+			it("falls back to the go directive", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "go.mod",
+					Version:       ">= 1.21",
+					Build:         true,
+				}))
+			})
+		})
+	})
+
+	context("when BP_GO_VERSION is set", func() {
+		it.Before(func() {
+			goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{
+				Go:        gomodvendor.Directive{Value: "1.21.3", Position: gomodvendor.Position{File: "go.mod", Line: 3}},
+				Toolchain: gomodvendor.Directive{Value: "go1.21.4"},
+			}
+			configParser.ParseCall.Returns.BuildConfiguration.GoVersion = "1.22.5"
+		})
+
+		it("requests that version instead of the go.mod version", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Requires: []packit.BuildPlanRequirement{
+					{
+						Name: "go",
+						Metadata: gomodvendor.BuildPlanMetadata{
+							VersionSource: "BP_GO_VERSION",
+							Version:       "1.22.5",
+							Build:         true,
+						},
+					},
+				},
+			}))
+		})
+
+		context("when the version is older than the toolchain but not the go directive", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.BuildConfiguration.GoVersion = "go1.21.3"
+			})
+
+			it("requests that version", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "BP_GO_VERSION",
+					Version:       "1.21.3",
+					Build:         true,
+				}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the version is lower than the go directive", func() {
+				it.Before(func() {
+					configParser.ParseCall.Returns.BuildConfiguration.GoVersion = "1.21.2"
+				})
+
+				it("returns an error", func() {
+					_, err := detect(detectContext)
+					Expect(err).To(MatchError("BP_GO_VERSION '1.21.2' is lower than go 1.21.3 required by go.mod:3"))
+				})
+			})
+
+			context("when the version is invalid", func() {
+				it.Before(func() {
+					configParser.ParseCall.Returns.BuildConfiguration.GoVersion = "latest"
+				})
+
+				it("returns an error", func() {
+					_, err := detect(detectContext)
+					Expect(err).To(MatchError(`failed to parse BP_GO_VERSION: invalid go version "latest"`))
+				})
+			})
+		})
+	})
+
+	context("when several modules are configured", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+			configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{".", "tools"}
+
+			goModParser.ParseCall.Stub = func(path string) (gomodvendor.GoMod, error) {
+				if path == filepath.Join(workingDir, "tools", "go.mod") {
+					return gomodvendor.GoMod{Go: gomodvendor.Directive{Value: "1.22.3"}}, nil
+				}
+				return gomodvendor.GoMod{Go: gomodvendor.Directive{Value: "1.21"}}, nil
+			}
+		})
+
+		it("requests the highest version any module needs", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan.Requires).To(HaveLen(1))
+			Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+				VersionSource: "go.mod",
+				Version:       ">= 1.22.3",
+				Build:         true,
+			}))
+
+			Expect(goModParser.ParseCall.CallCount).To(Equal(2))
+		})
+
+		context("when one of the modules has no go.mod file", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "tools", "go.mod"))).To(Succeed())
+			})
+
+			it("fails detection", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(packit.Fail.WithMessage("go.mod file is not present in 'tools'")))
+			})
+		})
+	})
+
+	context("when the working directory contains a go.work file", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+			Expect(os.Remove(filepath.Join(workingDir, "go.mod"))).To(Succeed())
+
+			goModParser.ParseWorkCall.Returns.GoWork = gomodvendor.GoWork{
+				Go: gomodvendor.Directive{Value: "1.21"},
+				Use: []gomodvendor.Use{
+					{Path: "./api"},
+					{Path: "./worker"},
+				},
+			}
+
+			goModParser.ParseCall.Stub = func(path string) (gomodvendor.GoMod, error) {
+				switch path {
+				case filepath.Join(workingDir, "api", "go.mod"):
+					return gomodvendor.GoMod{Go: gomodvendor.Directive{Value: "1.22.1"}}, nil
+				case filepath.Join(workingDir, "worker", "go.mod"):
+					return gomodvendor.GoMod{Go: gomodvendor.Directive{Value: "1.21.4"}}, nil
+				}
+
+				return gomodvendor.GoMod{}, fmt.Errorf("unexpected path %s", path)
+			}
+		})
+
+		it("requests the highest version any workspace module needs", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Requires: []packit.BuildPlanRequirement{
+					{
+						Name: "go",
+						Metadata: gomodvendor.BuildPlanMetadata{
+							VersionSource: "go.mod",
+							Version:       ">= 1.22.1",
+							Build:         true,
+						},
+					},
+				},
+			}))
+
+			Expect(goModParser.ParseWorkCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.work")))
+			Expect(goModParser.ParseCall.CallCount).To(Equal(2))
+		})
+
+		context("when the go.work file requires the highest version", func() {
+			it.Before(func() {
+				goModParser.ParseWorkCall.Returns.GoWork.Go = gomodvendor.Directive{Value: "1.23"}
+			})
+
+			it("requests the go.work version", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "go.work",
+					Version:       ">= 1.23",
+					Build:         true,
+				}))
+			})
+		})
+
+		context("when every workspace file declares a version older than go work vendor", func() {
+			it.Before(func() {
+				goModParser.ParseWorkCall.Returns.GoWork.Go = gomodvendor.Directive{Value: "1.18"}
+				goModParser.ParseCall.Stub = func(path string) (gomodvendor.GoMod, error) {
+					return gomodvendor.GoMod{Go: gomodvendor.Directive{Value: "1.20"}}, nil
+				}
+			})
+
+			it("requests Go 1.22 or later", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "go.work",
+					Version:       ">= 1.22",
+					Build:         true,
+				}))
+			})
+
+			context("when the vendor mode is download", func() {
+				it.Before(func() {
+					configParser.ParseCall.Returns.BuildConfiguration.VendorMode = "download"
+				})
+
+				it("requests the declared version", func() {
+					result, err := detect(detectContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+						VersionSource: "go.mod",
+						Version:       ">= 1.20",
+						Build:         true,
+					}))
+				})
+			})
+
+			context("when BP_GO_VERSION is older than go work vendor", func() {
+				it.Before(func() {
+					configParser.ParseCall.Returns.BuildConfiguration.GoVersion = "1.21"
+				})
+
+				it("returns an error", func() {
+					_, err := detect(detectContext)
+					Expect(err).To(MatchError("BP_GO_VERSION '1.21' is lower than go 1.22 required by 'go work vendor'"))
+				})
+			})
+		})
+
+		context("when the go.work file has a toolchain directive", func() {
+			it.Before(func() {
+				goModParser.ParseWorkCall.Returns.GoWork.Toolchain = gomodvendor.Directive{Value: "go1.22.5"}
+			})
+
+			it("requests the exact toolchain version", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "toolchain",
+					Version:       "1.22.5",
+					Build:         true,
+				}))
+			})
+		})
+
+		context("when workspaces are disabled", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+				configParser.ParseCall.Returns.BuildConfiguration.DisableWorkspace = true
+
+				goModParser.ParseCall.Stub = nil
+				goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{Go: gomodvendor.Directive{Value: "1.20"}}
+			})
+
+			it("only reads the go.mod file in the working directory", func() {
+				result, err := detect(detectContext)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+					VersionSource: "go.mod",
+					Version:       ">= 1.20",
+					Build:         true,
+				}))
+
+				Expect(goModParser.ParseWorkCall.CallCount).To(Equal(0))
+				Expect(goModParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.mod")))
+			})
+		})
+
+		context("failure cases", func() {
+			context("the go.work file cannot be parsed", func() {
+				it.Before(func() {
+					goModParser.ParseWorkCall.Returns.Error = errors.New("failed to parse go.work")
+				})
+
+				it("returns an error", func() {
+					_, err := detect(detectContext)
+					Expect(err).To(MatchError("failed to parse go.work"))
+				})
+			})
+
+			context("a workspace module cannot be parsed", func() {
+				it.Before(func() {
+					goModParser.ParseWorkCall.Returns.GoWork.Use = append(goModParser.ParseWorkCall.Returns.GoWork.Use, gomodvendor.Use{Path: "./missing"})
+				})
+
+				it("returns an error", func() {
+					_, err := detect(detectContext)
+					Expect(err).To(MatchError(fmt.Sprintf("unexpected path %s", filepath.Join(workingDir, "missing", "go.mod"))))
+				})
+			})
+		})
+	})
+
+	context("when the module is in a sub-directory", func() {
+		it.Before(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "go.mod"))).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(workingDir, "services", "api"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "services", "api", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+			configParser.ParseCall.Returns.BuildConfiguration.ModulePaths = []string{"services/api"}
+		})
+
+		it("parses the go.mod file in that directory", func() {
+			result, err := detect(detectContext)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Plan.Requires[0].Metadata).To(Equal(gomodvendor.BuildPlanMetadata{
+				VersionSource: "go.mod",
+				Version:       ">= 1.15",
+				Build:         true,
+			}))
+
+			Expect(configParser.ParseCall.Receives.WorkingDir).To(Equal(workingDir))
+			Expect(goModParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "services", "api", "go.mod")))
+		})
+
+		context("when the go.mod file is not in that directory", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "services", "api", "go.mod"))).To(Succeed())
+			})
+
+			it("fails detection", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(packit.Fail.WithMessage("go.mod file is not present in 'services/api'")))
+			})
+		})
 	})
 
 	context("go.mod does not exist in the working directory", func() {
@@ -74,6 +576,61 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			_, err := detect(detectContext)
 			Expect(err).To(MatchError(packit.Fail.WithMessage("go.mod file is not present")))
 		})
+
+		context("when a dep manifest is present", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gopkg.toml"), nil, os.ModePerm)).To(Succeed())
+			})
+
+			it("fails detection with a migration hint", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(packit.Fail.WithMessage("go.mod file is not present, but Gopkg.toml from dep was found: migrate the app to Go modules, or set BP_GO_MOD_MIGRATE=true to generate go.mod from Gopkg.lock during the build")))
+			})
+
+			context("when the migration is enabled", func() {
+				it.Before(func() {
+					configParser.ParseCall.Returns.BuildConfiguration.MigrateLegacy = true
+				})
+
+				it("detects without a version constraint", func() {
+					result, err := detect(detectContext)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Plan).To(Equal(packit.BuildPlan{
+						Requires: []packit.BuildPlanRequirement{
+							{
+								Name: "go",
+								Metadata: gomodvendor.BuildPlanMetadata{
+									Build: true,
+								},
+							},
+						},
+					}))
+				})
+			})
+		})
+
+		context("when only a dep lock file is present", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gopkg.lock"), nil, os.ModePerm)).To(Succeed())
+			})
+
+			it("names the lock file in the migration hint", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(packit.Fail.WithMessage("go.mod file is not present, but Gopkg.lock from dep was found: migrate the app to Go modules, or set BP_GO_MOD_MIGRATE=true to generate go.mod from Gopkg.lock during the build")))
+			})
+		})
+
+		context("when a glide manifest is present", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "glide.yaml"), nil, os.ModePerm)).To(Succeed())
+			})
+
+			it("fails detection with a migration hint", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(packit.Fail.WithMessage("go.mod file is not present, but glide.yaml from glide was found: migrate the app to Go modules, or set BP_GO_MOD_MIGRATE=true to generate go.mod from glide.lock during the build")))
+			})
+		})
 	})
 
 	context("when there is a vendor directory in the working directory", func() {
@@ -101,6 +658,17 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	context("failure cases", func() {
+		context("the build configuration cannot be parsed", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.Error = errors.New("failed to parse configuration")
+			})
+
+			it("returns an error", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError("failed to parse configuration"))
+			})
+		})
+
 		context("there is an error determining if the go.mod file exists", func() {
 			it.Before(func() {
 				Expect(os.Chmod(workingDir, 0000)).To(Succeed())
@@ -118,7 +686,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 
 		context("the go.mod file cannot be read", func() {
 			it.Before(func() {
-				goModParser.ParseVersionCall.Returns.Err = errors.New("some error")
+				goModParser.ParseCall.Returns.Error = errors.New("some error")
 			})
 
 			it("returns an error", func() {
@@ -126,5 +694,57 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				Expect(err).To(MatchError("some error"))
 			})
 		})
+
+		context("the go directive is invalid", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod.Go = gomodvendor.Directive{
+					Value:    "1.21.1rc1",
+					Position: gomodvendor.Position{File: "go.mod", Line: 3},
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(`go.mod:3: invalid go version "1.21.1rc1"`))
+			})
+		})
+
+		context("the go.mod file is malformed", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n\nrequire (\n\texample.com/dep\n)\n"), os.ModePerm)).To(Succeed())
+
+				detect = gomodvendor.Detect(configParser, gomodvendor.NewGoModParser(), scribe.NewEmitter(logs))
+			})
+
+			it("returns an error with the file and line of the problem", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("failed to parse go.mod: %s:6:", filepath.Join(workingDir, "go.mod")))))
+			})
+		})
+
+		context("the go.work file has no go directive and uses no modules", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError("failed to determine the go version: no go or toolchain directive found"))
+			})
+		})
+
+		context("the toolchain directive is invalid", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod.Toolchain = gomodvendor.Directive{
+					Value:    "go1.x",
+					Position: gomodvendor.Position{File: "go.mod", Line: 5},
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := detect(detectContext)
+				Expect(err).To(MatchError(`go.mod:5: invalid toolchain "go1.x"`))
+			})
+		})
 	})
 }
@@ -1,53 +1,266 @@
 package fakes
 
-import "sync"
+import (
+	"sync"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+)
 
 type BuildProcess struct {
+	CheckTidyCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path       string
+			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, string, gomodvendor.BuildConfiguration) error
+	}
 	ExecuteCall struct {
 		mutex     sync.Mutex
 		CallCount int
 		Receives  struct {
 			Path       string
+			VendorPath string
 			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
 		}
 		Returns struct {
 			Error error
 		}
-		Stub func(string, string) error
+		Stub func(string, string, string, gomodvendor.BuildConfiguration) error
+	}
+	LocalReplacementsCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			RootDir    string
+			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
+		}
+		Returns struct {
+			LocalReplacementSlice []gomodvendor.LocalReplacement
+			Error                 error
+		}
+		Stub func(string, string, gomodvendor.BuildConfiguration) ([]gomodvendor.LocalReplacement, error)
+	}
+	ModCacheSettingsCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
+		}
+		Returns struct {
+			ModCacheSettings gomodvendor.ModCacheSettings
+			Error            error
+		}
+		Stub func(string, gomodvendor.BuildConfiguration) (gomodvendor.ModCacheSettings, error)
+	}
+	PruneCacheCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path        string
+			WorkingDirs []string
+			Config      gomodvendor.BuildConfiguration
+		}
+		Returns struct {
+			StringSlice []string
+			Error       error
+		}
+		Stub func(string, []string, gomodvendor.BuildConfiguration) ([]string, error)
 	}
 	ShouldRunCall struct {
 		mutex     sync.Mutex
 		CallCount int
 		Receives  struct {
 			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
 		}
 		Returns struct {
 			Ok     bool
 			Reason string
 			Err    error
 		}
-		Stub func(string) (bool, string, error)
+		Stub func(string, gomodvendor.BuildConfiguration) (bool, string, error)
+	}
+	ToolModulesCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path       string
+			VendorPath string
+			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
+		}
+		Returns struct {
+			StringSlice []string
+			Error       error
+		}
+		Stub func(string, string, string, gomodvendor.BuildConfiguration) ([]string, error)
+	}
+	VendorDigestCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
+		}
+		Returns struct {
+			Digest gomodvendor.VendorDigest
+			Ok     bool
+			Err    error
+		}
+		Stub func(string, gomodvendor.BuildConfiguration) (gomodvendor.VendorDigest, bool, error)
+	}
+	VendorKeyCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
+		}
+		Returns struct {
+			String string
+			Error  error
+		}
+		Stub func(string, gomodvendor.BuildConfiguration) (string, error)
+	}
+	VerifyCacheCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path       string
+			WorkingDir string
+			Config     gomodvendor.BuildConfiguration
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, string, gomodvendor.BuildConfiguration) error
 	}
 }
 
-func (f *BuildProcess) Execute(param1 string, param2 string) error {
+func (f *BuildProcess) CheckTidy(param1 string, param2 string, param3 gomodvendor.BuildConfiguration) error {
+	f.CheckTidyCall.mutex.Lock()
+	defer f.CheckTidyCall.mutex.Unlock()
+	f.CheckTidyCall.CallCount++
+	f.CheckTidyCall.Receives.Path = param1
+	f.CheckTidyCall.Receives.WorkingDir = param2
+	f.CheckTidyCall.Receives.Config = param3
+	if f.CheckTidyCall.Stub != nil {
+		return f.CheckTidyCall.Stub(param1, param2, param3)
+	}
+	return f.CheckTidyCall.Returns.Error
+}
+func (f *BuildProcess) Execute(param1 string, param2 string, param3 string, param4 gomodvendor.BuildConfiguration) error {
 	f.ExecuteCall.mutex.Lock()
 	defer f.ExecuteCall.mutex.Unlock()
 	f.ExecuteCall.CallCount++
 	f.ExecuteCall.Receives.Path = param1
-	f.ExecuteCall.Receives.WorkingDir = param2
+	f.ExecuteCall.Receives.VendorPath = param2
+	f.ExecuteCall.Receives.WorkingDir = param3
+	f.ExecuteCall.Receives.Config = param4
 	if f.ExecuteCall.Stub != nil {
-		return f.ExecuteCall.Stub(param1, param2)
+		return f.ExecuteCall.Stub(param1, param2, param3, param4)
 	}
 	return f.ExecuteCall.Returns.Error
 }
-func (f *BuildProcess) ShouldRun(param1 string) (bool, string, error) {
+func (f *BuildProcess) LocalReplacements(param1 string, param2 string, param3 gomodvendor.BuildConfiguration) ([]gomodvendor.LocalReplacement, error) {
+	f.LocalReplacementsCall.mutex.Lock()
+	defer f.LocalReplacementsCall.mutex.Unlock()
+	f.LocalReplacementsCall.CallCount++
+	f.LocalReplacementsCall.Receives.RootDir = param1
+	f.LocalReplacementsCall.Receives.WorkingDir = param2
+	f.LocalReplacementsCall.Receives.Config = param3
+	if f.LocalReplacementsCall.Stub != nil {
+		return f.LocalReplacementsCall.Stub(param1, param2, param3)
+	}
+	return f.LocalReplacementsCall.Returns.LocalReplacementSlice, f.LocalReplacementsCall.Returns.Error
+}
+func (f *BuildProcess) ModCacheSettings(param1 string, param2 gomodvendor.BuildConfiguration) (gomodvendor.ModCacheSettings, error) {
+	f.ModCacheSettingsCall.mutex.Lock()
+	defer f.ModCacheSettingsCall.mutex.Unlock()
+	f.ModCacheSettingsCall.CallCount++
+	f.ModCacheSettingsCall.Receives.WorkingDir = param1
+	f.ModCacheSettingsCall.Receives.Config = param2
+	if f.ModCacheSettingsCall.Stub != nil {
+		return f.ModCacheSettingsCall.Stub(param1, param2)
+	}
+	return f.ModCacheSettingsCall.Returns.ModCacheSettings, f.ModCacheSettingsCall.Returns.Error
+}
+func (f *BuildProcess) PruneCache(param1 string, param2 []string, param3 gomodvendor.BuildConfiguration) ([]string, error) {
+	f.PruneCacheCall.mutex.Lock()
+	defer f.PruneCacheCall.mutex.Unlock()
+	f.PruneCacheCall.CallCount++
+	f.PruneCacheCall.Receives.Path = param1
+	f.PruneCacheCall.Receives.WorkingDirs = param2
+	f.PruneCacheCall.Receives.Config = param3
+	if f.PruneCacheCall.Stub != nil {
+		return f.PruneCacheCall.Stub(param1, param2, param3)
+	}
+	return f.PruneCacheCall.Returns.StringSlice, f.PruneCacheCall.Returns.Error
+}
+func (f *BuildProcess) ShouldRun(param1 string, param2 gomodvendor.BuildConfiguration) (bool, string, error) {
 	f.ShouldRunCall.mutex.Lock()
 	defer f.ShouldRunCall.mutex.Unlock()
 	f.ShouldRunCall.CallCount++
 	f.ShouldRunCall.Receives.WorkingDir = param1
+	f.ShouldRunCall.Receives.Config = param2
 	if f.ShouldRunCall.Stub != nil {
-		return f.ShouldRunCall.Stub(param1)
+		return f.ShouldRunCall.Stub(param1, param2)
 	}
 	return f.ShouldRunCall.Returns.Ok, f.ShouldRunCall.Returns.Reason, f.ShouldRunCall.Returns.Err
 }
+func (f *BuildProcess) ToolModules(param1 string, param2 string, param3 string, param4 gomodvendor.BuildConfiguration) ([]string, error) {
+	f.ToolModulesCall.mutex.Lock()
+	defer f.ToolModulesCall.mutex.Unlock()
+	f.ToolModulesCall.CallCount++
+	f.ToolModulesCall.Receives.Path = param1
+	f.ToolModulesCall.Receives.VendorPath = param2
+	f.ToolModulesCall.Receives.WorkingDir = param3
+	f.ToolModulesCall.Receives.Config = param4
+	if f.ToolModulesCall.Stub != nil {
+		return f.ToolModulesCall.Stub(param1, param2, param3, param4)
+	}
+	return f.ToolModulesCall.Returns.StringSlice, f.ToolModulesCall.Returns.Error
+}
+func (f *BuildProcess) VendorDigest(param1 string, param2 gomodvendor.BuildConfiguration) (gomodvendor.VendorDigest, bool, error) {
+	f.VendorDigestCall.mutex.Lock()
+	defer f.VendorDigestCall.mutex.Unlock()
+	f.VendorDigestCall.CallCount++
+	f.VendorDigestCall.Receives.WorkingDir = param1
+	f.VendorDigestCall.Receives.Config = param2
+	if f.VendorDigestCall.Stub != nil {
+		return f.VendorDigestCall.Stub(param1, param2)
+	}
+	return f.VendorDigestCall.Returns.Digest, f.VendorDigestCall.Returns.Ok, f.VendorDigestCall.Returns.Err
+}
+func (f *BuildProcess) VendorKey(param1 string, param2 gomodvendor.BuildConfiguration) (string, error) {
+	f.VendorKeyCall.mutex.Lock()
+	defer f.VendorKeyCall.mutex.Unlock()
+	f.VendorKeyCall.CallCount++
+	f.VendorKeyCall.Receives.WorkingDir = param1
+	f.VendorKeyCall.Receives.Config = param2
+	if f.VendorKeyCall.Stub != nil {
+		return f.VendorKeyCall.Stub(param1, param2)
+	}
+	return f.VendorKeyCall.Returns.String, f.VendorKeyCall.Returns.Error
+}
+func (f *BuildProcess) VerifyCache(param1 string, param2 string, param3 gomodvendor.BuildConfiguration) error {
+	f.VerifyCacheCall.mutex.Lock()
+	defer f.VerifyCacheCall.mutex.Unlock()
+	f.VerifyCacheCall.CallCount++
+	f.VerifyCacheCall.Receives.Path = param1
+	f.VerifyCacheCall.Receives.WorkingDir = param2
+	f.VerifyCacheCall.Receives.Config = param3
+	if f.VerifyCacheCall.Stub != nil {
+		return f.VerifyCacheCall.Stub(param1, param2, param3)
+	}
+	return f.VerifyCacheCall.Returns.Error
+}
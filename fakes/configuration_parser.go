@@ -0,0 +1,33 @@
+package fakes
+
+import (
+	"sync"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+)
+
+type ConfigurationParser struct {
+	ParseCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir string
+		}
+		Returns struct {
+			BuildConfiguration gomodvendor.BuildConfiguration
+			Error              error
+		}
+		Stub func(string) (gomodvendor.BuildConfiguration, error)
+	}
+}
+
+func (f *ConfigurationParser) Parse(param1 string) (gomodvendor.BuildConfiguration, error) {
+	f.ParseCall.mutex.Lock()
+	defer f.ParseCall.mutex.Unlock()
+	f.ParseCall.CallCount++
+	f.ParseCall.Receives.WorkingDir = param1
+	if f.ParseCall.Stub != nil {
+		return f.ParseCall.Stub(param1)
+	}
+	return f.ParseCall.Returns.BuildConfiguration, f.ParseCall.Returns.Error
+}
@@ -0,0 +1,99 @@
+package fakes
+
+import (
+	"sync"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+)
+
+type ModuleParser struct {
+	ParseGoSumCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			GoSum gomodvendor.GoSum
+			Error error
+		}
+		Stub func(string) (gomodvendor.GoSum, error)
+	}
+	ParseCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			GoMod gomodvendor.GoMod
+			Error error
+		}
+		Stub func(string) (gomodvendor.GoMod, error)
+	}
+	ParseModulesTxtCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			ModulesTxt gomodvendor.ModulesTxt
+			Error      error
+		}
+		Stub func(string) (gomodvendor.ModulesTxt, error)
+	}
+	ParseWorkCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			GoWork gomodvendor.GoWork
+			Error  error
+		}
+		Stub func(string) (gomodvendor.GoWork, error)
+	}
+}
+
+func (f *ModuleParser) ParseGoSum(param1 string) (gomodvendor.GoSum, error) {
+	f.ParseGoSumCall.mutex.Lock()
+	defer f.ParseGoSumCall.mutex.Unlock()
+	f.ParseGoSumCall.CallCount++
+	f.ParseGoSumCall.Receives.Path = param1
+	if f.ParseGoSumCall.Stub != nil {
+		return f.ParseGoSumCall.Stub(param1)
+	}
+	return f.ParseGoSumCall.Returns.GoSum, f.ParseGoSumCall.Returns.Error
+}
+func (f *ModuleParser) Parse(param1 string) (gomodvendor.GoMod, error) {
+	f.ParseCall.mutex.Lock()
+	defer f.ParseCall.mutex.Unlock()
+	f.ParseCall.CallCount++
+	f.ParseCall.Receives.Path = param1
+	if f.ParseCall.Stub != nil {
+		return f.ParseCall.Stub(param1)
+	}
+	return f.ParseCall.Returns.GoMod, f.ParseCall.Returns.Error
+}
+func (f *ModuleParser) ParseModulesTxt(param1 string) (gomodvendor.ModulesTxt, error) {
+	f.ParseModulesTxtCall.mutex.Lock()
+	defer f.ParseModulesTxtCall.mutex.Unlock()
+	f.ParseModulesTxtCall.CallCount++
+	f.ParseModulesTxtCall.Receives.Path = param1
+	if f.ParseModulesTxtCall.Stub != nil {
+		return f.ParseModulesTxtCall.Stub(param1)
+	}
+	return f.ParseModulesTxtCall.Returns.ModulesTxt, f.ParseModulesTxtCall.Returns.Error
+}
+func (f *ModuleParser) ParseWork(param1 string) (gomodvendor.GoWork, error) {
+	f.ParseWorkCall.mutex.Lock()
+	defer f.ParseWorkCall.mutex.Unlock()
+	f.ParseWorkCall.CallCount++
+	f.ParseWorkCall.Receives.Path = param1
+	if f.ParseWorkCall.Stub != nil {
+		return f.ParseWorkCall.Stub(param1)
+	}
+	return f.ParseWorkCall.Returns.GoWork, f.ParseWorkCall.Returns.Error
+}
@@ -3,6 +3,7 @@ package fakes
 import (
 	"sync"
 
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
 )
 
@@ -11,23 +12,27 @@ type SBOMGenerator struct {
 		mutex     sync.Mutex
 		CallCount int
 		Receives  struct {
-			Dir string
+			Paths        []string
+			LocalModules []gomodvendor.LocalReplacement
+			ToolModules  []string
 		}
 		Returns struct {
 			SBOM  sbom.SBOM
 			Error error
 		}
-		Stub func(string) (sbom.SBOM, error)
+		Stub func([]string, []gomodvendor.LocalReplacement, []string) (sbom.SBOM, error)
 	}
 }
 
-func (f *SBOMGenerator) Generate(param1 string) (sbom.SBOM, error) {
+func (f *SBOMGenerator) Generate(param1 []string, param2 []gomodvendor.LocalReplacement, param3 []string) (sbom.SBOM, error) {
 	f.GenerateCall.mutex.Lock()
 	defer f.GenerateCall.mutex.Unlock()
 	f.GenerateCall.CallCount++
-	f.GenerateCall.Receives.Dir = param1
+	f.GenerateCall.Receives.Paths = param1
+	f.GenerateCall.Receives.LocalModules = param2
+	f.GenerateCall.Receives.ToolModules = param3
 	if f.GenerateCall.Stub != nil {
-		return f.GenerateCall.Stub(param1)
+		return f.GenerateCall.Stub(param1, param2, param3)
 	}
 	return f.GenerateCall.Returns.SBOM, f.GenerateCall.Returns.Error
 }
@@ -2,35 +2,379 @@ package gomodvendor
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
-	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/modfile"
 )
 
+// Position identifies the line in a module file where a directive was
+// declared.
+type Position struct {
+	File string
+	Line int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+type ModuleVersion struct {
+	Path    string
+	Version string
+}
+
+func (v ModuleVersion) String() string {
+	if v.Version == "" {
+		return v.Path
+	}
+
+	return fmt.Sprintf("%s %s", v.Path, v.Version)
+}
+
+type Module struct {
+	Path       string
+	Deprecated string
+	Position   Position
+}
+
+type Directive struct {
+	Value    string
+	Position Position
+}
+
+type Require struct {
+	ModuleVersion
+	Indirect bool
+	Position Position
+}
+
+type Replace struct {
+	Old      ModuleVersion
+	New      ModuleVersion
+	Position Position
+}
+
+type Exclude struct {
+	ModuleVersion
+	Position Position
+}
+
+type Retract struct {
+	Low       string
+	High      string
+	Rationale string
+	Position  Position
+}
+
+type Tool struct {
+	Path     string
+	Position Position
+}
+
+type Godebug struct {
+	Key      string
+	Value    string
+	Position Position
+}
+
+// GoMod is the structured content of a go.mod file. Directives that are not
+// declared in the file are left as their zero value.
+type GoMod struct {
+	Path      string
+	Module    Module
+	Go        Directive
+	Toolchain Directive
+	Require   []Require
+	Replace   []Replace
+	Exclude   []Exclude
+	Retract   []Retract
+	Tool      []Tool
+	Godebug   []Godebug
+}
+
+// ToolModules returns the required modules that provide the packages named by
+// the tool directives, sorted by path. A tool can also be a package of the
+// main module or of one of the given workspace modules, which is not
+// reported.
+func (g GoMod) ToolModules(workspace map[string]bool) []string {
+	var modules []string
+	for _, tool := range g.Tool {
+		if providesPackage(g.Module.Path, tool.Path) {
+			continue
+		}
+
+		var module string
+		for _, require := range g.Require {
+			if providesPackage(require.Path, tool.Path) && len(require.Path) > len(module) {
+				module = require.Path
+			}
+		}
+
+		if module == "" || workspace[module] || slices.Contains(modules, module) {
+			continue
+		}
+
+		modules = append(modules, module)
+	}
+
+	slices.Sort(modules)
+
+	return modules
+}
+
+// providesPackage reports whether the package path belongs to the module
+// path, ignoring nested modules.
+func providesPackage(module, pkg string) bool {
+	return module != "" && (pkg == module || strings.HasPrefix(pkg, module+"/"))
+}
+
+type Use struct {
+	Path       string
+	ModulePath string
+	Position   Position
+}
+
+// GoWork is the structured content of a go.work file.
+type GoWork struct {
+	Path      string
+	Go        Directive
+	Toolchain Directive
+	Use       []Use
+	Replace   []Replace
+	Godebug   []Godebug
+}
+
 type GoModParser struct{}
 
 func NewGoModParser() GoModParser {
 	return GoModParser{}
 }
 
-func (p GoModParser) ParseVersion(path string) (string, error) {
-	file, err := os.Open(path)
+func (p GoModParser) Parse(path string) (GoMod, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return GoMod{}, fmt.Errorf("failed to parse go.mod: %w", err)
+	}
+
+	file, err := modfile.Parse(path, content, nil)
+	if err != nil {
+		return GoMod{}, fmt.Errorf("failed to parse go.mod: %w", err)
+	}
+
+	if file.Module == nil {
+		return GoMod{}, fmt.Errorf("failed to parse go.mod: %s: no module declaration", path)
+	}
+
+	position := linePosition(path)
+
+	goMod := GoMod{
+		Path: path,
+		Module: Module{
+			Path:       file.Module.Mod.Path,
+			Deprecated: file.Module.Deprecated,
+			Position:   position(file.Module.Syntax),
+		},
+	}
+
+	if file.Go != nil {
+		goMod.Go = Directive{Value: file.Go.Version, Position: position(file.Go.Syntax)}
+	}
+
+	if file.Toolchain != nil {
+		goMod.Toolchain = Directive{Value: file.Toolchain.Name, Position: position(file.Toolchain.Syntax)}
+	}
+
+	for _, r := range file.Require {
+		goMod.Require = append(goMod.Require, Require{
+			ModuleVersion: ModuleVersion{Path: r.Mod.Path, Version: r.Mod.Version},
+			Indirect:      r.Indirect,
+			Position:      position(r.Syntax),
+		})
+	}
+
+	for _, r := range file.Replace {
+		goMod.Replace = append(goMod.Replace, Replace{
+			Old:      ModuleVersion{Path: r.Old.Path, Version: r.Old.Version},
+			New:      ModuleVersion{Path: r.New.Path, Version: r.New.Version},
+			Position: position(r.Syntax),
+		})
+	}
+
+	for _, e := range file.Exclude {
+		goMod.Exclude = append(goMod.Exclude, Exclude{
+			ModuleVersion: ModuleVersion{Path: e.Mod.Path, Version: e.Mod.Version},
+			Position:      position(e.Syntax),
+		})
+	}
+
+	for _, r := range file.Retract {
+		goMod.Retract = append(goMod.Retract, Retract{
+			Low:       r.Low,
+			High:      r.High,
+			Rationale: r.Rationale,
+			Position:  position(r.Syntax),
+		})
+	}
+
+	for _, t := range file.Tool {
+		goMod.Tool = append(goMod.Tool, Tool{Path: t.Path, Position: position(t.Syntax)})
+	}
+
+	for _, g := range file.Godebug {
+		goMod.Godebug = append(goMod.Godebug, Godebug{Key: g.Key, Value: g.Value, Position: position(g.Syntax)})
+	}
+
+	return goMod, nil
+}
+
+func (p GoModParser) ParseWork(path string) (GoWork, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return GoWork{}, fmt.Errorf("failed to parse go.work: %w", err)
+	}
+
+	file, err := modfile.ParseWork(path, content, nil)
+	if err != nil {
+		return GoWork{}, fmt.Errorf("failed to parse go.work: %w", err)
+	}
+
+	position := linePosition(path)
+
+	goWork := GoWork{Path: path}
+
+	if file.Go != nil {
+		goWork.Go = Directive{Value: file.Go.Version, Position: position(file.Go.Syntax)}
+	}
+
+	if file.Toolchain != nil {
+		goWork.Toolchain = Directive{Value: file.Toolchain.Name, Position: position(file.Toolchain.Syntax)}
+	}
+
+	for _, u := range file.Use {
+		goWork.Use = append(goWork.Use, Use{Path: u.Path, ModulePath: u.ModulePath, Position: position(u.Syntax)})
+	}
+
+	for _, r := range file.Replace {
+		goWork.Replace = append(goWork.Replace, Replace{
+			Old:      ModuleVersion{Path: r.Old.Path, Version: r.Old.Version},
+			New:      ModuleVersion{Path: r.New.Path, Version: r.New.Version},
+			Position: position(r.Syntax),
+		})
+	}
+
+	for _, g := range file.Godebug {
+		goWork.Godebug = append(goWork.Godebug, Godebug{Key: g.Key, Value: g.Value, Position: position(g.Syntax)})
+	}
+
+	return goWork, nil
+}
+
+func (p GoModParser) ParseModulesTxt(path string) (ModulesTxt, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ModulesTxt{}, fmt.Errorf("failed to parse modules.txt: %w", err)
+	}
+
+	modulesTxt := ModulesTxt{Path: path}
+
+	var current *VendoredModule
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "## "):
+			if current == nil {
+				return ModulesTxt{}, fmt.Errorf("failed to parse modules.txt: %s:%d: annotation without a module", path, lineNumber)
+			}
+
+			for _, annotation := range strings.Split(strings.TrimPrefix(line, "## "), ";") {
+				annotation = strings.TrimSpace(annotation)
+				switch {
+				case annotation == "explicit":
+					current.Explicit = true
+				case strings.HasPrefix(annotation, "go "):
+					current.GoVersion = strings.TrimPrefix(annotation, "go ")
+				}
+			}
+
+		case strings.HasPrefix(line, "# "):
+			fields := strings.Fields(strings.TrimPrefix(line, "# "))
+
+			var module VendoredModule
+			if i := slices.Index(fields, "=>"); i >= 0 {
+				if i == 0 || i > 2 || len(fields) < i+2 || len(fields) > i+3 {
+					return ModulesTxt{}, fmt.Errorf("failed to parse modules.txt: %s:%d: malformed module line", path, lineNumber)
+				}
+
+				module.Replacement.Path = fields[i+1]
+				if len(fields) == i+3 {
+					module.Replacement.Version = fields[i+2]
+				}
+				fields = fields[:i]
+			}
+
+			if len(fields) == 0 || len(fields) > 2 {
+				return ModulesTxt{}, fmt.Errorf("failed to parse modules.txt: %s:%d: malformed module line", path, lineNumber)
+			}
+
+			module.Path = fields[0]
+			if len(fields) == 2 {
+				module.Version = fields[1]
+			}
+
+			modulesTxt.Modules = append(modulesTxt.Modules, module)
+			current = &modulesTxt.Modules[len(modulesTxt.Modules)-1]
+
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+
+		default:
+			if current == nil {
+				return ModulesTxt{}, fmt.Errorf("failed to parse modules.txt: %s:%d: package without a module", path, lineNumber)
+			}
+
+			current.Packages = append(current.Packages, line)
+		}
+	}
+
+	return modulesTxt, nil
+}
+
+func (p GoModParser) ParseGoSum(path string) (GoSum, error) {
+	content, err := os.ReadFile(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse go.mod: %w", err)
+		return GoSum{}, fmt.Errorf("failed to parse go.sum: %w", err)
 	}
 
-	versionNumber := `\d+\.\d+`
-	expression := fmt.Sprintf(`go (%s)`, versionNumber)
-	re := regexp.MustCompile(expression)
+	goSum := GoSum{Path: path}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		matches := re.FindStringSubmatch(scanner.Text())
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
 
-		if len(matches) == 2 {
-			return fmt.Sprintf(">= %s", matches[1]), nil
+		if len(fields) != 3 {
+			return GoSum{}, fmt.Errorf("failed to parse go.sum: %s:%d: malformed line", path, lineNumber)
 		}
+
+		goSum.Entries = append(goSum.Entries, SumEntry{
+			ModuleVersion: ModuleVersion{Path: fields[0], Version: fields[1]},
+			Hash:          fields[2],
+		})
 	}
 
-	return "", nil
+	return goSum, nil
+}
+
+func linePosition(path string) func(*modfile.Line) Position {
+	return func(line *modfile.Line) Position {
+		return Position{File: path, Line: line.Start.Line}
+	}
 }
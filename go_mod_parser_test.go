@@ -26,14 +26,30 @@ func testGoModParser(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).NotTo(HaveOccurred())
 		}()
 
-		_, err = file.WriteString(`module github.com/some-org/some-repo
+		_, err = file.WriteString(`// Deprecated: use github.com/some-org/other-repo
+module github.com/some-org/some-repo
 
-go 1.15
+// bump to go 1.18 once the stack allows it
+go 1.21
+
+toolchain go1.22.5
+
+godebug default=go1.21
 
 require (
 	github.com/some/dependency v0.3.1
-	github.com/some-other/dependency v0.0.4
+	github.com/some-other/dependency v0.0.4 // indirect
 )
+
+replace github.com/some/dependency => ../dependency
+
+replace github.com/some-other/dependency v0.0.4 => github.com/fork/dependency v0.0.5
+
+exclude github.com/some/dependency v0.3.0
+
+retract [v1.0.0, v1.0.5] // published by accident
+
+tool github.com/some/dependency/cmd/generate
 `)
 		Expect(err).NotTo(HaveOccurred())
 
@@ -46,11 +62,74 @@ require (
 		Expect(os.RemoveAll(path)).To(Succeed())
 	})
 
-	context("ParseVersion", func() {
-		it("parses the go version from a go.mod file", func() {
-			version, err := parser.ParseVersion(path)
+	context("Parse", func() {
+		it("parses every directive in the go.mod file", func() {
+			goMod, err := parser.Parse(path)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(version).To(Equal(">= 1.15"))
+
+			Expect(goMod).To(Equal(gomodvendor.GoMod{
+				Path: path,
+				Module: gomodvendor.Module{
+					Path:       "github.com/some-org/some-repo",
+					Deprecated: "use github.com/some-org/other-repo",
+					Position:   gomodvendor.Position{File: path, Line: 2},
+				},
+				Go:        gomodvendor.Directive{Value: "1.21", Position: gomodvendor.Position{File: path, Line: 5}},
+				Toolchain: gomodvendor.Directive{Value: "go1.22.5", Position: gomodvendor.Position{File: path, Line: 7}},
+				Godebug: []gomodvendor.Godebug{
+					{Key: "default", Value: "go1.21", Position: gomodvendor.Position{File: path, Line: 9}},
+				},
+				Require: []gomodvendor.Require{
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v0.3.1"},
+						Position:      gomodvendor.Position{File: path, Line: 12},
+					},
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"},
+						Indirect:      true,
+						Position:      gomodvendor.Position{File: path, Line: 13},
+					},
+				},
+				Replace: []gomodvendor.Replace{
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "github.com/some/dependency"},
+						New:      gomodvendor.ModuleVersion{Path: "../dependency"},
+						Position: gomodvendor.Position{File: path, Line: 16},
+					},
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"},
+						New:      gomodvendor.ModuleVersion{Path: "github.com/fork/dependency", Version: "v0.0.5"},
+						Position: gomodvendor.Position{File: path, Line: 18},
+					},
+				},
+				Exclude: []gomodvendor.Exclude{
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v0.3.0"},
+						Position:      gomodvendor.Position{File: path, Line: 20},
+					},
+				},
+				Retract: []gomodvendor.Retract{
+					{Low: "v1.0.0", High: "v1.0.5", Rationale: "published by accident", Position: gomodvendor.Position{File: path, Line: 22}},
+				},
+				Tool: []gomodvendor.Tool{
+					{Path: "github.com/some/dependency/cmd/generate", Position: gomodvendor.Position{File: path, Line: 24}},
+				},
+			}))
+		})
+
+		context("when the go.mod only declares a module", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte("module github.com/some-org/some-repo\n"), 0600)).To(Succeed())
+			})
+
+			it("leaves the other directives empty", func() {
+				goMod, err := parser.Parse(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(goMod.Module.Path).To(Equal("github.com/some-org/some-repo"))
+				Expect(goMod.Go).To(Equal(gomodvendor.Directive{}))
+				Expect(goMod.Toolchain).To(Equal(gomodvendor.Directive{}))
+				Expect(goMod.Require).To(BeEmpty())
+			})
 		})
 
 		context("failure cases", func() {
@@ -60,11 +139,270 @@ require (
 				})
 
 				it("returns an error", func() {
-					_, err := parser.ParseVersion(path)
+					_, err := parser.Parse(path)
 					Expect(err).To(MatchError(ContainSubstring("failed to parse go.mod:")))
 					Expect(err).To(MatchError(ContainSubstring("permission denied")))
 				})
 			})
+
+			context("when the go.mod contains an unknown directive", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("module github.com/some-org/some-repo\n\ngo 1.21\nrequires github.com/some/dependency v0.3.1\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error with the line of the directive", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse go.mod:")))
+					Expect(err).To(MatchError(ContainSubstring(path + `:4: unknown directive: requires`)))
+				})
+			})
+
+			context("when the go directive is malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("module github.com/some-org/some-repo\n\ngo 1.x\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error with the line of the directive", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(ContainSubstring(path + `:3: invalid go version '1.x'`)))
+				})
+			})
+
+			context("when a require has a malformed version", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("module github.com/some-org/some-repo\n\nrequire github.com/some/dependency latest\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error with the line of the directive", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(ContainSubstring(path + `:3:`)))
+					Expect(err).To(MatchError(ContainSubstring("github.com/some/dependency")))
+				})
+			})
+
+			context("when there is no module declaration", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("go 1.21\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(path)
+					Expect(err).To(MatchError(ContainSubstring(path + ": no module declaration")))
+				})
+			})
+		})
+	})
+	context("ParseWork", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`go 1.22
+
+toolchain go1.22.5
+
+use (
+	./api
+	./worker
+)
+
+replace example.com/shared => ./shared
+`), 0600)).To(Succeed())
+		})
+
+		it("parses every directive in the go.work file", func() {
+			goWork, err := parser.ParseWork(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(goWork).To(Equal(gomodvendor.GoWork{
+				Path:      path,
+				Go:        gomodvendor.Directive{Value: "1.22", Position: gomodvendor.Position{File: path, Line: 1}},
+				Toolchain: gomodvendor.Directive{Value: "go1.22.5", Position: gomodvendor.Position{File: path, Line: 3}},
+				Use: []gomodvendor.Use{
+					{Path: "./api", Position: gomodvendor.Position{File: path, Line: 6}},
+					{Path: "./worker", Position: gomodvendor.Position{File: path, Line: 7}},
+				},
+				Replace: []gomodvendor.Replace{
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "example.com/shared"},
+						New:      gomodvendor.ModuleVersion{Path: "./shared"},
+						Position: gomodvendor.Position{File: path, Line: 10},
+					},
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the go.work cannot be opened", func() {
+				it.Before(func() {
+					Expect(os.Chmod(path, 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseWork(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse go.work:")))
+					Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				})
+			})
+
+			context("when the go.work contains a go.mod directive", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("go 1.22\n\nrequire example.com/shared v1.0.0\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error with the line of the directive", func() {
+					_, err := parser.ParseWork(path)
+					Expect(err).To(MatchError(ContainSubstring(path + ":3: unknown directive: require")))
+				})
+			})
+		})
+	})
+
+	context("ParseModulesTxt", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`# github.com/some/dependency v0.3.1 => ../dependency
+## explicit; go 1.21
+github.com/some/dependency
+github.com/some/dependency/internal
+# github.com/some-other/dependency v0.0.4
+github.com/some-other/dependency
+# github.com/unused/dependency => github.com/fork/dependency v0.0.5
+`), 0600)).To(Succeed())
+		})
+
+		it("parses every module in the modules.txt file", func() {
+			modulesTxt, err := parser.ParseModulesTxt(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(modulesTxt).To(Equal(gomodvendor.ModulesTxt{
+				Path: path,
+				Modules: []gomodvendor.VendoredModule{
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v0.3.1"},
+						Replacement:   gomodvendor.ModuleVersion{Path: "../dependency"},
+						Explicit:      true,
+						GoVersion:     "1.21",
+						Packages:      []string{"github.com/some/dependency", "github.com/some/dependency/internal"},
+					},
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"},
+						Packages:      []string{"github.com/some-other/dependency"},
+					},
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/unused/dependency"},
+						Replacement:   gomodvendor.ModuleVersion{Path: "github.com/fork/dependency", Version: "v0.0.5"},
+					},
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the modules.txt cannot be opened", func() {
+				it.Before(func() {
+					Expect(os.Chmod(path, 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseModulesTxt(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse modules.txt:")))
+					Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				})
+			})
+
+			context("when a package is listed before any module", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("github.com/some/dependency\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error with the line", func() {
+					_, err := parser.ParseModulesTxt(path)
+					Expect(err).To(MatchError(ContainSubstring(path + ":1: package without a module")))
+				})
+			})
+
+			context("when a module line is malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("# github.com/some/dependency v0.3.1 =>\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error with the line", func() {
+					_, err := parser.ParseModulesTxt(path)
+					Expect(err).To(MatchError(ContainSubstring(path + ":1: malformed module line")))
+				})
+			})
+		})
+	})
+
+	context("ParseGoSum", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`github.com/some/dependency v0.3.1 h1:abc=
+github.com/some/dependency v0.3.1/go.mod h1:def=
+
+github.com/some-other/dependency v0.0.4/go.mod h1:ghi=
+`), 0600)).To(Succeed())
+		})
+
+		it("parses every entry in the go.sum file", func() {
+			goSum, err := parser.ParseGoSum(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(goSum).To(Equal(gomodvendor.GoSum{
+				Path: path,
+				Entries: []gomodvendor.SumEntry{
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v0.3.1"}, Hash: "h1:abc="},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v0.3.1/go.mod"}, Hash: "h1:def="},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4/go.mod"}, Hash: "h1:ghi="},
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the go.sum cannot be opened", func() {
+				it.Before(func() {
+					Expect(os.Chmod(path, 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseGoSum(path)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse go.sum:")))
+					Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				})
+			})
+
+			context("when a line is malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(path, []byte("github.com/some/dependency v0.3.1\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error with the line", func() {
+					_, err := parser.ParseGoSum(path)
+					Expect(err).To(MatchError(ContainSubstring(path + ":1: malformed line")))
+				})
+			})
+		})
+	})
+
+	context("GoMod.ToolModules", func() {
+		it("returns the required modules that provide the tools", func() {
+			goMod := gomodvendor.GoMod{
+				Module: gomodvendor.Module{Path: "example.com/server"},
+				Require: []gomodvendor.Require{
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/tool", Version: "v1.0.0"}},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/tool/v2", Version: "v2.0.0"}},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/other/linter", Version: "v0.1.0"}},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "example.com/shared", Version: "v0.0.0"}},
+				},
+				Tool: []gomodvendor.Tool{
+					{Path: "github.com/some/tool/v2/cmd/tool"},
+					{Path: "github.com/other/linter"},
+					{Path: "github.com/other/linter/cmd/fix"},
+					{Path: "example.com/server/cmd/generate"},
+					{Path: "example.com/shared/cmd/shared"},
+					{Path: "github.com/unknown/tool"},
+				},
+			}
+
+			Expect(goMod.ToolModules(map[string]bool{"example.com/shared": true})).To(Equal([]string{
+				"github.com/other/linter",
+				"github.com/some/tool/v2",
+			}))
 		})
 	})
 }
@@ -0,0 +1,112 @@
+package gomodvendor
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// SumEntry is a line of a go.sum file. The version of the checksum of a
+// go.mod file ends with "/go.mod".
+type SumEntry struct {
+	ModuleVersion
+	Hash string
+}
+
+// GoSum is the structured content of a go.sum (or go.work.sum) file.
+type GoSum struct {
+	Path    string
+	Entries []SumEntry
+}
+
+// Merge returns the entries of both files, as the go command does when it
+// reads go.work.sum alongside the go.sum files of a workspace.
+func (s GoSum) Merge(other GoSum) GoSum {
+	return GoSum{
+		Path:    s.Path,
+		Entries: append(append([]SumEntry{}, s.Entries...), other.Entries...),
+	}
+}
+
+func (s GoSum) has(path, version string) bool {
+	for _, entry := range s.Entries {
+		if entry.Path == path && entry.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Missing returns the go.sum lines that the requirements of go.mod need but
+// that are absent, in the "path version" or "path version/go.mod" form. Every
+// required module needs the checksum of its go.mod file. Only direct
+// requirements are known to provide packages, so the checksum of the module
+// content is only required for them: a tidy go.sum omits it for indirect
+// requirements that are only needed for version selection. Modules replaced
+// by a local directory and the given workspace modules have no checksum.
+// The go command reports the missing content checksum of an indirect
+// requirement that provides packages, see missingSumPackages.
+func (s GoSum) Missing(goMod GoMod, replace []Replace, workspace map[string]bool) []string {
+	var missing []string
+	for _, r := range goMod.Require {
+		if workspace[r.Path] {
+			continue
+		}
+
+		module := r.ModuleVersion
+		if replacement, ok := findReplacement(append(append([]Replace{}, replace...), goMod.Replace...), module); ok {
+			if replacement.Version == "" {
+				continue
+			}
+
+			module = replacement
+		}
+
+		if !s.has(module.Path, module.Version+"/go.mod") {
+			missing = append(missing, fmt.Sprintf("%s %s/go.mod", module.Path, module.Version))
+		}
+
+		if !r.Indirect && !s.has(module.Path, module.Version) {
+			missing = append(missing, fmt.Sprintf("%s %s", module.Path, module.Version))
+		}
+	}
+
+	return missing
+}
+
+// findReplacement returns the replacement that applies to the module. A
+// replacement of the exact version takes priority over one for every version,
+// and earlier replacements (those of go.work) take priority over later ones.
+func findReplacement(replace []Replace, module ModuleVersion) (ModuleVersion, bool) {
+	for _, r := range replace {
+		if r.Old.Path == module.Path && r.Old.Version == module.Version {
+			return r.New, true
+		}
+	}
+
+	for _, r := range replace {
+		if r.Old.Path == module.Path && r.Old.Version == "" {
+			return r.New, true
+		}
+	}
+
+	return ModuleVersion{}, false
+}
+
+var missingSumPattern = regexp.MustCompile(`missing go\.sum entry for module providing package (\S+)`)
+
+// missingSumPackages returns the packages whose module has no checksum in
+// go.sum, from the output of a failed go command.
+func missingSumPackages(output string) []string {
+	var packages []string
+	for _, match := range missingSumPattern.FindAllStringSubmatch(output, -1) {
+		if !slices.Contains(packages, match[1]) {
+			packages = append(packages, match[1])
+		}
+	}
+
+	slices.Sort(packages)
+
+	return packages
+}
@@ -0,0 +1,113 @@
+package gomodvendor_test
+
+import (
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testGoSum(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		goMod gomodvendor.GoMod
+		goSum gomodvendor.GoSum
+	)
+
+	it.Before(func() {
+		goMod = gomodvendor.GoMod{
+			Require: []gomodvendor.Require{
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}},
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"}, Indirect: true},
+			},
+		}
+
+		goSum = gomodvendor.GoSum{
+			Entries: []gomodvendor.SumEntry{
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}, Hash: "h1:abc="},
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3/go.mod"}, Hash: "h1:def="},
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4/go.mod"}, Hash: "h1:ghi="},
+			},
+		}
+	})
+
+	context("Missing", func() {
+		it("returns nothing when every required entry is present", func() {
+			Expect(goSum.Missing(goMod, nil, nil)).To(BeEmpty())
+		})
+
+		context("when entries are missing", func() {
+			it.Before(func() {
+				goSum.Entries = goSum.Entries[1:]
+				goMod.Require = append(goMod.Require, gomodvendor.Require{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/new/dependency", Version: "v0.1.0"},
+					Indirect:      true,
+				})
+			})
+
+			it("lists them", func() {
+				Expect(goSum.Missing(goMod, nil, nil)).To(Equal([]string{
+					"github.com/some/dependency v1.2.3",
+					"github.com/new/dependency v0.1.0/go.mod",
+				}))
+			})
+		})
+
+		context("when a required module is replaced", func() {
+			it.Before(func() {
+				goMod.Replace = []gomodvendor.Replace{
+					{
+						Old: gomodvendor.ModuleVersion{Path: "github.com/some/dependency"},
+						New: gomodvendor.ModuleVersion{Path: "github.com/fork/dependency", Version: "v1.2.4"},
+					},
+					{
+						Old: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"},
+						New: gomodvendor.ModuleVersion{Path: "../other"},
+					},
+				}
+			})
+
+			it("needs the entries of the replacement, unless it is a directory", func() {
+				Expect(goSum.Missing(goMod, nil, nil)).To(Equal([]string{
+					"github.com/fork/dependency v1.2.4/go.mod",
+					"github.com/fork/dependency v1.2.4",
+				}))
+			})
+
+			context("when go.work replaces it too", func() {
+				it("prefers the replacement of go.work", func() {
+					Expect(goSum.Missing(goMod, []gomodvendor.Replace{
+						{
+							Old: gomodvendor.ModuleVersion{Path: "github.com/some/dependency"},
+							New: gomodvendor.ModuleVersion{Path: "./dependency"},
+						},
+					}, nil)).To(BeEmpty())
+				})
+			})
+		})
+
+		context("when a required module is part of the workspace", func() {
+			it.Before(func() {
+				goSum.Entries = nil
+			})
+
+			it("skips it", func() {
+				Expect(goSum.Missing(goMod, nil, map[string]bool{
+					"github.com/some/dependency":       true,
+					"github.com/some-other/dependency": true,
+				})).To(BeEmpty())
+			})
+		})
+	})
+
+	context("Merge", func() {
+		it("combines the entries of both files", func() {
+			merged := gomodvendor.GoSum{Path: "go.work.sum"}.Merge(goSum)
+			Expect(merged.Path).To(Equal("go.work.sum"))
+			Expect(merged.Entries).To(Equal(goSum.Entries))
+		})
+	})
+}
@@ -0,0 +1,162 @@
+package gomodvendor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var goVersionPattern = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?(?:(alpha|beta|rc)(\d+))?$`)
+
+// GoVersion is a version as written in a go or toolchain directive. A version
+// without a patch or pre-release component ("1.21") is a language version,
+// which sorts before every release of that minor line.
+type GoVersion struct {
+	Major      int
+	Minor      int
+	Patch      int
+	HasPatch   bool
+	Prerelease string
+	Number     int
+}
+
+func ParseGoVersion(version string) (GoVersion, error) {
+	matches := goVersionPattern.FindStringSubmatch(version)
+	if matches == nil {
+		return GoVersion{}, fmt.Errorf("invalid go version %q", version)
+	}
+
+	var v GoVersion
+	v.Major, _ = strconv.Atoi(matches[1])
+	v.Minor, _ = strconv.Atoi(matches[2])
+
+	if matches[3] != "" {
+		v.Patch, _ = strconv.Atoi(matches[3])
+		v.HasPatch = true
+	}
+
+	if matches[4] != "" {
+		if v.HasPatch {
+			return GoVersion{}, fmt.Errorf("invalid go version %q", version)
+		}
+
+		v.Prerelease = matches[4]
+		v.Number, _ = strconv.Atoi(matches[5])
+	}
+
+	return v, nil
+}
+
+// ParseToolchain parses the name given to a toolchain directive, for example
+// "go1.22.5" or "go1.23rc1". Any suffix following a "-" or "+" is ignored, as
+// the go command does when comparing toolchains. The "default" toolchain has
+// no version and is reported as not ok.
+func ParseToolchain(name string) (GoVersion, bool, error) {
+	if name == "" || name == "default" {
+		return GoVersion{}, false, nil
+	}
+
+	version, found := strings.CutPrefix(name, "go")
+	if !found {
+		return GoVersion{}, false, fmt.Errorf("invalid toolchain %q", name)
+	}
+
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+
+	v, err := ParseGoVersion(version)
+	if err != nil {
+		return GoVersion{}, false, fmt.Errorf("invalid toolchain %q", name)
+	}
+
+	return v, true, nil
+}
+
+var semverPrereleasePattern = regexp.MustCompile(`^(\d+)\.(\d+)\.0-(alpha|beta|rc)\.(\d+)$`)
+
+// ParseVersionOverride parses a version given through BP_GO_VERSION. Besides
+// the forms accepted by ParseGoVersion, it accepts a "go" prefix
+// ("go1.22.5") and the semver form of pre-releases ("1.23.0-rc.1").
+func ParseVersionOverride(version string) (GoVersion, error) {
+	trimmed := strings.TrimPrefix(version, "go")
+	if matches := semverPrereleasePattern.FindStringSubmatch(trimmed); matches != nil {
+		trimmed = fmt.Sprintf("%s.%s%s%s", matches[1], matches[2], matches[3], matches[4])
+	}
+
+	v, err := ParseGoVersion(trimmed)
+	if err != nil {
+		return GoVersion{}, fmt.Errorf("invalid go version %q", version)
+	}
+
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 depending on whether v sorts before, equal to or
+// after other, following the go command's ordering:
+// 1.21 < 1.21rc1 < 1.21.0 < 1.21.1.
+func (v GoVersion) Compare(other GoVersion) int {
+	for _, pair := range [][2]int{
+		{v.Major, other.Major},
+		{v.Minor, other.Minor},
+		{v.kind(), other.kind()},
+		{v.Number, other.Number},
+		{v.Patch, other.Patch},
+	} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func (v GoVersion) kind() int {
+	switch {
+	case v.HasPatch:
+		return 4
+	case v.Prerelease == "rc":
+		return 3
+	case v.Prerelease == "beta":
+		return 2
+	case v.Prerelease == "alpha":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Semver returns the version in the semantic versioning form used by the
+// go-dist buildpack, for example "1.22.5" or "1.23.0-rc.1".
+func (v GoVersion) Semver() string {
+	if v.Prerelease != "" {
+		return fmt.Sprintf("%d.%d.0-%s.%d", v.Major, v.Minor, v.Prerelease, v.Number)
+	}
+
+	if !v.HasPatch {
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Constraint returns a semver constraint matching this version and any later
+// release, preserving its patch and pre-release components.
+func (v GoVersion) Constraint() string {
+	return fmt.Sprintf(">= %s", v.Semver())
+}
+
+func (v GoVersion) String() string {
+	switch {
+	case v.Prerelease != "":
+		return fmt.Sprintf("%d.%d%s%d", v.Major, v.Minor, v.Prerelease, v.Number)
+	case v.HasPatch:
+		return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	default:
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+}
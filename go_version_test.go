@@ -0,0 +1,167 @@
+package gomodvendor_test
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testGoVersion(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("ParseGoVersion", func() {
+		it("parses a language version", func() {
+			version, err := gomodvendor.ParseGoVersion("1.21")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(gomodvendor.GoVersion{Major: 1, Minor: 21}))
+			Expect(version.String()).To(Equal("1.21"))
+		})
+
+		it("parses a release version", func() {
+			version, err := gomodvendor.ParseGoVersion("1.22.5")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(gomodvendor.GoVersion{Major: 1, Minor: 22, Patch: 5, HasPatch: true}))
+			Expect(version.String()).To(Equal("1.22.5"))
+		})
+
+		it("parses a pre-release version", func() {
+			version, err := gomodvendor.ParseGoVersion("1.23rc1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(gomodvendor.GoVersion{Major: 1, Minor: 23, Prerelease: "rc", Number: 1}))
+			Expect(version.String()).To(Equal("1.23rc1"))
+		})
+
+		context("failure cases", func() {
+			it("returns an error for a malformed version", func() {
+				_, err := gomodvendor.ParseGoVersion("1.x")
+				Expect(err).To(MatchError(`invalid go version "1.x"`))
+			})
+
+			it("returns an error for a pre-release with a patch", func() {
+				_, err := gomodvendor.ParseGoVersion("1.22.1rc1")
+				Expect(err).To(MatchError(`invalid go version "1.22.1rc1"`))
+			})
+		})
+	})
+
+	context("ParseVersionOverride", func() {
+		it("parses every accepted form", func() {
+			for version, expected := range map[string]string{
+				"1.22.5":      "1.22.5",
+				"go1.22.5":    "1.22.5",
+				"1.22":        "1.22",
+				"1.23rc1":     "1.23rc1",
+				"1.23.0-rc.1": "1.23rc1",
+			} {
+				v, err := gomodvendor.ParseVersionOverride(version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(v.String()).To(Equal(expected))
+			}
+		})
+
+		context("failure cases", func() {
+			it("returns an error for a constraint", func() {
+				_, err := gomodvendor.ParseVersionOverride("~1.22")
+				Expect(err).To(MatchError(`invalid go version "~1.22"`))
+			})
+		})
+	})
+
+	context("ParseToolchain", func() {
+		it("parses the toolchain version", func() {
+			version, ok, err := gomodvendor.ParseToolchain("go1.22.5")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(version.String()).To(Equal("1.22.5"))
+		})
+
+		it("ignores a custom suffix", func() {
+			version, ok, err := gomodvendor.ParseToolchain("go1.22.5-custom+auto")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(version.String()).To(Equal("1.22.5"))
+		})
+
+		it("reports the default toolchain as having no version", func() {
+			_, ok, err := gomodvendor.ParseToolchain("default")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the name does not start with go", func() {
+				_, _, err := gomodvendor.ParseToolchain("1.22.5")
+				Expect(err).To(MatchError(`invalid toolchain "1.22.5"`))
+			})
+		})
+	})
+
+	context("Compare", func() {
+		it("orders versions the way the go command does", func() {
+			ordered := []string{"1.20.14", "1.21", "1.21beta1", "1.21rc1", "1.21rc2", "1.21.0", "1.21.1", "1.22"}
+			for i := 0; i < len(ordered)-1; i++ {
+				lower, err := gomodvendor.ParseGoVersion(ordered[i])
+				Expect(err).NotTo(HaveOccurred())
+
+				higher, err := gomodvendor.ParseGoVersion(ordered[i+1])
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(lower.Compare(higher)).To(Equal(-1), "%s < %s", ordered[i], ordered[i+1])
+				Expect(higher.Compare(lower)).To(Equal(1), "%s > %s", ordered[i+1], ordered[i])
+				Expect(lower.Compare(lower)).To(Equal(0))
+			}
+		})
+	})
+
+	context("Constraint", func() {
+		it("converts every form of go version to a valid semver constraint", func() {
+			for version, constraint := range map[string]string{
+				"1.21":       ">= 1.21",
+				"1.21.0":     ">= 1.21.0",
+				"1.21.3":     ">= 1.21.3",
+				"1.22rc1":    ">= 1.22.0-rc.1",
+				"1.22beta2":  ">= 1.22.0-beta.2",
+				"1.22alpha1": ">= 1.22.0-alpha.1",
+			} {
+				v, err := gomodvendor.ParseGoVersion(version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(v.Constraint()).To(Equal(constraint))
+
+				_, err = semver.NewConstraint(v.Constraint())
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+
+		it("matches releases that satisfy the go directive", func() {
+			v, err := gomodvendor.ParseGoVersion("1.21.3")
+			Expect(err).NotTo(HaveOccurred())
+
+			constraint, err := semver.NewConstraint(v.Constraint())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(constraint.Check(semver.MustParse("1.21.2"))).To(BeFalse())
+			Expect(constraint.Check(semver.MustParse("1.21.3"))).To(BeTrue())
+			Expect(constraint.Check(semver.MustParse("1.22.0"))).To(BeTrue())
+		})
+	})
+
+	context("Semver", func() {
+		it("converts versions to semver", func() {
+			for version, semver := range map[string]string{
+				"1.21":      "1.21",
+				"1.21.0":    "1.21.0",
+				"1.22.5":    "1.22.5",
+				"1.23rc1":   "1.23.0-rc.1",
+				"1.23beta2": "1.23.0-beta.2",
+			} {
+				v, err := gomodvendor.ParseGoVersion(version)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(v.Semver()).To(Equal(semver))
+			}
+		})
+	})
+}
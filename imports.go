@@ -0,0 +1,243 @@
+package gomodvendor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// moduleImports returns the packages imported by the packages of the module
+// in dir, sorted. Test files are only read when tests is set. The vendor and
+// testdata directories, those starting with . or _, and nested modules are
+// skipped, as the go command does.
+func moduleImports(dir string, tests bool) ([]string, error) {
+	found := map[string]bool{}
+	err := filepath.WalkDir(dir, func(path string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if path != dir {
+			name := entry.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+
+			if _, err := os.Stat(filepath.Join(path, GoModLocation)); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		imports, err := packageImports(path, tests)
+		if err != nil {
+			return err
+		}
+
+		for _, imported := range imports {
+			found[imported] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []string
+	for imported := range found {
+		imports = append(imports, imported)
+	}
+
+	slices.Sort(imports)
+
+	return imports, nil
+}
+
+// moduleLocation is the directory holding the packages of a module.
+type moduleLocation struct {
+	module string
+	dir    string
+}
+
+// locatePackage returns the module that provides the given package, among
+// the given modules, along with the directory of the package. The module
+// with the longest path that is a prefix of the package is chosen, as the go
+// command does.
+func locatePackage(pkg string, locations []moduleLocation) (moduleLocation, bool) {
+	var found moduleLocation
+	for _, location := range locations {
+		if len(location.module) <= len(found.module) {
+			continue
+		}
+
+		if pkg == location.module || strings.HasPrefix(pkg, location.module+"/") {
+			found = location
+		}
+	}
+
+	if found.module == "" {
+		return moduleLocation{}, false
+	}
+
+	rel := strings.TrimPrefix(strings.TrimPrefix(pkg, found.module), "/")
+
+	return moduleLocation{module: found.module, dir: filepath.Join(found.dir, filepath.FromSlash(rel))}, true
+}
+
+// reachableModules follows the imports of the packages of the modules in
+// the given directories, skipping their tests, and returns the modules that
+// provide every package reached, sorted. locate tells where a package is and
+// which module provides it; the standard library is not found.
+func reachableModules(roots []string, locate func(string) (moduleLocation, bool)) ([]string, error) {
+	var queue []string
+	for _, root := range roots {
+		imports, err := moduleImports(root, false)
+		if err != nil {
+			return nil, err
+		}
+
+		queue = append(queue, imports...)
+	}
+
+	seen := map[string]bool{}
+	found := map[string]bool{}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+
+		if seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+
+		location, ok := locate(pkg)
+		if !ok {
+			continue
+		}
+		found[location.module] = true
+
+		imports, err := packageImports(location.dir, false)
+		if err != nil {
+			// A package that is not vendored or downloaded is left for the go
+			// command to report when the app is compiled.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		queue = append(queue, imports...)
+	}
+
+	var modules []string
+	for module := range found {
+		modules = append(modules, module)
+	}
+
+	slices.Sort(modules)
+
+	return modules, nil
+}
+
+// packageImports returns the packages imported by the Go files of the
+// package in dir. Like go mod vendor, it reads the files of every platform
+// and build tag, except those excluded by the "ignore" tag.
+func packageImports(dir string, tests bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []string
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		if !tests && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the imports of %s: %w", filepath.Join(dir, name), err)
+		}
+
+		if !matchAnyTags(file) {
+			continue
+		}
+
+		for _, spec := range file.Imports {
+			imported, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || imported == "C" || slices.Contains(imports, imported) {
+				continue
+			}
+
+			imports = append(imports, imported)
+		}
+	}
+
+	return imports, nil
+}
+
+// matchAnyTags reports whether the build constraints of the file can be
+// satisfied by some set of build tags other than "ignore".
+func matchAnyTags(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) && !constraint.IsPlusBuild(comment.Text) {
+				continue
+			}
+
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				continue
+			}
+
+			if !evalAnyTags(expr, true) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// evalAnyTags evaluates a build constraint the way the go command does when
+// it gathers the imports for every build: each tag but "ignore" is taken as
+// both set and unset, whichever satisfies the expression.
+func evalAnyTags(expr constraint.Expr, prefer bool) bool {
+	switch expr := expr.(type) {
+	case *constraint.TagExpr:
+		return expr.Tag != "ignore" && prefer
+	case *constraint.NotExpr:
+		return !evalAnyTags(expr.X, !prefer)
+	case *constraint.AndExpr:
+		return evalAnyTags(expr.X, prefer) && evalAnyTags(expr.Y, prefer)
+	case *constraint.OrExpr:
+		return evalAnyTags(expr.X, prefer) || evalAnyTags(expr.Y, prefer)
+	}
+
+	return false
+}
@@ -10,8 +10,20 @@ import (
 func TestUnitGoModVendor(t *testing.T) {
 	suite := spec.New("go-mod-vendor", spec.Report(report.Terminal{}))
 	suite("Build", testBuild)
+	suite("Build Configuration Parser", testBuildConfigurationParser)
 	suite("Detect", testDetect)
+	suite("Mod Cache", testModCache)
+	suite("Mod Cache Settings", testModCacheSettings)
 	suite("Mod Vendor", testModVendor)
+	suite("Module Proxy", testModuleProxy)
+	suite("Module SBOM Generator", testModuleSBOMGenerator)
 	suite("Go Mod Parser", testGoModParser)
+	suite("Go Sum", testGoSum)
+	suite("Go Version", testGoVersion)
+	suite("Legacy Manifest", testLegacyManifest)
+	suite("Local Replacement", testLocalReplacement)
+	suite("Modules Txt", testModulesTxt)
+	suite("Vendor Digest", testVendorDigest)
+	suite("Vendor Snapshot", testVendorSnapshot)
 	suite.Run(t)
 }
@@ -0,0 +1,147 @@
+package gomodvendor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/packit/v2/fs"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// LegacyManifest describes the files of a pre-modules dependency manager
+// found in a module directory.
+type LegacyManifest struct {
+	Tool     string
+	Manifest string
+	LockFile string
+
+	// Found is the manifest or lock file that FindLegacyManifest found.
+	Found string
+}
+
+// LegacyProject is a dependency pinned by the lock file of a legacy
+// dependency manager.
+type LegacyProject struct {
+	Name     string
+	Revision string
+	Version  string
+}
+
+var legacyManifests = []LegacyManifest{
+	{Tool: "dep", Manifest: "Gopkg.toml", LockFile: "Gopkg.lock"},
+	{Tool: "glide", Manifest: "glide.yaml", LockFile: "glide.lock"},
+}
+
+// FindLegacyManifest returns the legacy dependency manager whose manifest or
+// lock file is present in the given directory.
+func FindLegacyManifest(dir string) (LegacyManifest, bool, error) {
+	for _, manifest := range legacyManifests {
+		for _, name := range []string{manifest.LockFile, manifest.Manifest} {
+			exists, err := fs.Exists(filepath.Join(dir, name))
+			if err != nil {
+				return LegacyManifest{}, false, err
+			}
+
+			if exists {
+				manifest.Found = name
+				return manifest, true, nil
+			}
+		}
+	}
+
+	return LegacyManifest{}, false, nil
+}
+
+// Projects reads the pinned dependencies from the lock file.
+func (m LegacyManifest) Projects(dir string) ([]LegacyProject, error) {
+	path := filepath.Join(dir, m.LockFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to migrate from %s: %s is required to pin dependency versions", m.Tool, m.LockFile)
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", m.LockFile, err)
+	}
+
+	var projects []LegacyProject
+	switch m.Tool {
+	case "dep":
+		var lock struct {
+			Projects []struct {
+				Name     string `toml:"name"`
+				Revision string `toml:"revision"`
+				Version  string `toml:"version"`
+			} `toml:"projects"`
+		}
+
+		_, err = toml.Decode(string(content), &lock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", m.LockFile, err)
+		}
+
+		for _, p := range lock.Projects {
+			projects = append(projects, LegacyProject{Name: p.Name, Revision: p.Revision, Version: p.Version})
+		}
+
+	case "glide":
+		var lock struct {
+			Imports []struct {
+				Name    string `yaml:"name"`
+				Version string `yaml:"version"`
+			} `yaml:"imports"`
+		}
+
+		err = yaml.Unmarshal(content, &lock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", m.LockFile, err)
+		}
+
+		// glide.lock always pins the resolved commit in the version field.
+		for _, i := range lock.Imports {
+			projects = append(projects, LegacyProject{Name: i.Name, Revision: i.Version})
+		}
+	}
+
+	return projects, nil
+}
+
+// ModulePath reads the import path of the app from the manifest, if the
+// legacy dependency manager records it.
+func (m LegacyManifest) ModulePath(dir string) (string, error) {
+	if m.Tool != "glide" {
+		return "", nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, m.Manifest))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s: %w", m.Manifest, err)
+	}
+
+	var manifest struct {
+		Package string `yaml:"package"`
+	}
+
+	err = yaml.Unmarshal(content, &manifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", m.Manifest, err)
+	}
+
+	return manifest.Package, nil
+}
+
+// Query returns the version query to pass to go get for the project. A
+// semver tag is preferred over the pinned revision so that the resulting
+// requirement reads as a release rather than a pseudo-version.
+func (p LegacyProject) Query() string {
+	if semver.IsValid(p.Version) {
+		return fmt.Sprintf("%s@%s", p.Name, p.Version)
+	}
+
+	return fmt.Sprintf("%s@%s", p.Name, p.Revision)
+}
@@ -0,0 +1,145 @@
+package gomodvendor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLegacyManifest(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("FindLegacyManifest", func() {
+		it("finds nothing in a directory without legacy files", func() {
+			_, found, err := gomodvendor.FindLegacyManifest(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		context("when only a lock file is present", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "glide.lock"), nil, os.ModePerm)).To(Succeed())
+			})
+
+			it("finds the dependency manager", func() {
+				manifest, found, err := gomodvendor.FindLegacyManifest(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(manifest).To(Equal(gomodvendor.LegacyManifest{Tool: "glide", Manifest: "glide.yaml", LockFile: "glide.lock", Found: "glide.lock"}))
+			})
+		})
+	})
+
+	context("Projects", func() {
+		context("for dep", func() {
+			var manifest gomodvendor.LegacyManifest
+
+			it.Before(func() {
+				manifest = gomodvendor.LegacyManifest{Tool: "dep", Manifest: "Gopkg.toml", LockFile: "Gopkg.lock"}
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gopkg.lock"), []byte(`
+[[projects]]
+  branch = "master"
+  name = "github.com/some/dependency"
+  packages = ["."]
+  revision = "0123456789abcdef0123456789abcdef01234567"
+
+[[projects]]
+  name = "github.com/some-other/dependency"
+  revision = "89abcdef0123456789abcdef0123456789abcdef"
+  version = "v0.4.1"
+
+[solve-meta]
+  inputs-digest = "abc"
+`), os.ModePerm)).To(Succeed())
+			})
+
+			it("reads the locked projects", func() {
+				projects, err := manifest.Projects(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(projects).To(Equal([]gomodvendor.LegacyProject{
+					{Name: "github.com/some/dependency", Revision: "0123456789abcdef0123456789abcdef01234567"},
+					{Name: "github.com/some-other/dependency", Revision: "89abcdef0123456789abcdef0123456789abcdef", Version: "v0.4.1"},
+				}))
+
+				Expect(projects[0].Query()).To(Equal("github.com/some/dependency@0123456789abcdef0123456789abcdef01234567"))
+				Expect(projects[1].Query()).To(Equal("github.com/some-other/dependency@v0.4.1"))
+			})
+
+			it("has no module path", func() {
+				path, err := manifest.ModulePath(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path).To(BeEmpty())
+			})
+
+			context("when the lock file is malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "Gopkg.lock"), []byte("[[projects"), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := manifest.Projects(workingDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse Gopkg.lock")))
+				})
+			})
+		})
+
+		context("for glide", func() {
+			var manifest gomodvendor.LegacyManifest
+
+			it.Before(func() {
+				manifest = gomodvendor.LegacyManifest{Tool: "glide", Manifest: "glide.yaml", LockFile: "glide.lock"}
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "glide.yaml"), []byte("package: github.com/some-org/some-app\nimport:\n- package: github.com/some/dependency\n"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "glide.lock"), []byte(`hash: abc
+updated: 2018-01-01T00:00:00Z
+imports:
+- name: github.com/some/dependency
+  version: 0123456789abcdef0123456789abcdef01234567
+testImports: []
+`), os.ModePerm)).To(Succeed())
+			})
+
+			it("reads the locked imports", func() {
+				projects, err := manifest.Projects(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(projects).To(Equal([]gomodvendor.LegacyProject{
+					{Name: "github.com/some/dependency", Revision: "0123456789abcdef0123456789abcdef01234567"},
+				}))
+			})
+
+			it("reads the module path from the manifest", func() {
+				path, err := manifest.ModulePath(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path).To(Equal("github.com/some-org/some-app"))
+			})
+		})
+
+		context("when the lock file is missing", func() {
+			it("returns an error", func() {
+				manifest := gomodvendor.LegacyManifest{Tool: "dep", Manifest: "Gopkg.toml", LockFile: "Gopkg.lock"}
+				_, err := manifest.Projects(workingDir)
+				Expect(err).To(MatchError("failed to migrate from dep: Gopkg.lock is required to pin dependency versions"))
+			})
+		})
+	})
+}
@@ -0,0 +1,109 @@
+package gomodvendor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+// LocalReplacement is a replace directive whose target is a directory inside
+// the working directory rather than a module version.
+type LocalReplacement struct {
+	Old ModuleVersion
+
+	// New is the target of the directive, as written in the module file.
+	New string
+
+	// Dir is the target directory, relative to the working directory.
+	Dir string
+
+	Position Position
+}
+
+func (r LocalReplacement) String() string {
+	return fmt.Sprintf("%s => %s", r.Old, r.New)
+}
+
+// FindLocalReplacements checks that every directory targeted by the given
+// replace directives exists, holds a go.mod file and lies inside the working
+// directory, which is the only directory available during the build. The
+// targets are resolved relative to the directory of the module file that
+// declares them.
+func FindLocalReplacements(workingDir, dir string, replaces []Replace) ([]LocalReplacement, error) {
+	root, err := filepath.EvalSymlinks(workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var replacements []LocalReplacement
+	for _, replace := range replaces {
+		if replace.New.Version != "" {
+			continue
+		}
+
+		target := replace.New.Path
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+
+		directive := fmt.Sprintf("replace %s => %s", replace.Old, replace.New.Path)
+
+		rel, err := filepath.Rel(workingDir, target)
+		if err != nil {
+			return nil, err
+		}
+
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			return nil, fmt.Errorf("%s: %s: '%s' is outside of the working directory and is not available during the build", replace.Position, directive, target)
+		}
+
+		info, err := os.Stat(target)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("%s: %s: '%s' does not exist", replace.Position, directive, target)
+			}
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s: %s: '%s' is not a directory", replace.Position, directive, target)
+		}
+
+		// A symlink inside the working directory may still point outside of
+		// it.
+		resolved, err := filepath.EvalSymlinks(target)
+		if err != nil {
+			return nil, err
+		}
+
+		rel, err = filepath.Rel(root, resolved)
+		if err != nil {
+			return nil, err
+		}
+
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			return nil, fmt.Errorf("%s: %s: '%s' is outside of the working directory and is not available during the build", replace.Position, directive, target)
+		}
+
+		exists, err := fs.Exists(filepath.Join(target, GoModLocation))
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, fmt.Errorf("%s: %s: '%s' does not contain a go.mod file", replace.Position, directive, target)
+		}
+
+		replacements = append(replacements, LocalReplacement{
+			Old:      replace.Old,
+			New:      replace.New.Path,
+			Dir:      filepath.ToSlash(rel),
+			Position: replace.Position,
+		})
+	}
+
+	return replacements, nil
+}
@@ -0,0 +1,151 @@
+package gomodvendor_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLocalReplacement(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		moduleDir  string
+		position   gomodvendor.Position
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		moduleDir = filepath.Join(workingDir, "services", "api")
+		Expect(os.MkdirAll(moduleDir, os.ModePerm)).To(Succeed())
+
+		Expect(os.MkdirAll(filepath.Join(workingDir, "libs", "shared"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "libs", "shared", "go.mod"), []byte("module example.com/shared\n"), os.ModePerm)).To(Succeed())
+
+		position = gomodvendor.Position{File: filepath.Join(moduleDir, "go.mod"), Line: 7}
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("FindLocalReplacements", func() {
+		it("returns the directory replacements relative to the working directory", func() {
+			replacements, err := gomodvendor.FindLocalReplacements(workingDir, moduleDir, []gomodvendor.Replace{
+				{
+					Old:      gomodvendor.ModuleVersion{Path: "example.com/shared"},
+					New:      gomodvendor.ModuleVersion{Path: "../../libs/shared"},
+					Position: position,
+				},
+				{
+					Old:      gomodvendor.ModuleVersion{Path: "github.com/some/dependency"},
+					New:      gomodvendor.ModuleVersion{Path: "github.com/some/fork", Version: "v1.2.3"},
+					Position: position,
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(replacements).To(Equal([]gomodvendor.LocalReplacement{
+				{
+					Old:      gomodvendor.ModuleVersion{Path: "example.com/shared"},
+					New:      "../../libs/shared",
+					Dir:      "libs/shared",
+					Position: position,
+				},
+			}))
+			Expect(replacements[0].String()).To(Equal("example.com/shared => ../../libs/shared"))
+		})
+
+		context("when the target is outside of the working directory", func() {
+			it("returns an error with the replace directive", func() {
+				_, err := gomodvendor.FindLocalReplacements(workingDir, moduleDir, []gomodvendor.Replace{
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "example.com/x"},
+						New:      gomodvendor.ModuleVersion{Path: "../../../x"},
+						Position: position,
+					},
+				})
+				Expect(err).To(MatchError(fmt.Sprintf("%s:7: replace example.com/x => ../../../x: '%s' is outside of the working directory and is not available during the build", filepath.Join(moduleDir, "go.mod"), filepath.Join(filepath.Dir(workingDir), "x"))))
+			})
+		})
+
+		context("when the target is a symlink to a directory outside of the working directory", func() {
+			var outsideDir string
+
+			it.Before(func() {
+				var err error
+				outsideDir, err = os.MkdirTemp("", "outside")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.Symlink(outsideDir, filepath.Join(workingDir, "libs", "outside"))).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.RemoveAll(outsideDir)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := gomodvendor.FindLocalReplacements(workingDir, moduleDir, []gomodvendor.Replace{
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "example.com/x"},
+						New:      gomodvendor.ModuleVersion{Path: "../../libs/outside"},
+						Position: position,
+					},
+				})
+				Expect(err).To(MatchError(ContainSubstring("replace example.com/x => ../../libs/outside: '%s' is outside of the working directory", filepath.Join(workingDir, "libs", "outside"))))
+			})
+		})
+
+		context("when the target does not exist", func() {
+			it("returns an error with the replace directive", func() {
+				_, err := gomodvendor.FindLocalReplacements(workingDir, moduleDir, []gomodvendor.Replace{
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "example.com/x", Version: "v1.0.0"},
+						New:      gomodvendor.ModuleVersion{Path: "../../libs/x"},
+						Position: position,
+					},
+				})
+				Expect(err).To(MatchError(fmt.Sprintf("%s:7: replace example.com/x v1.0.0 => ../../libs/x: '%s' does not exist", filepath.Join(moduleDir, "go.mod"), filepath.Join(workingDir, "libs", "x"))))
+			})
+		})
+
+		context("when the target has no go.mod file", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "libs", "shared", "go.mod"))).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := gomodvendor.FindLocalReplacements(workingDir, moduleDir, []gomodvendor.Replace{
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "example.com/shared"},
+						New:      gomodvendor.ModuleVersion{Path: "../../libs/shared"},
+						Position: position,
+					},
+				})
+				Expect(err).To(MatchError(ContainSubstring("'%s' does not contain a go.mod file", filepath.Join(workingDir, "libs", "shared"))))
+			})
+		})
+
+		context("when the target is a file", func() {
+			it("returns an error", func() {
+				_, err := gomodvendor.FindLocalReplacements(workingDir, moduleDir, []gomodvendor.Replace{
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "example.com/shared"},
+						New:      gomodvendor.ModuleVersion{Path: "../../libs/shared/go.mod"},
+						Position: position,
+					},
+				})
+				Expect(err).To(MatchError(ContainSubstring("'%s' is not a directory", filepath.Join(workingDir, "libs", "shared", "go.mod"))))
+			})
+		})
+	})
+}
@@ -0,0 +1,473 @@
+package gomodvendor
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+// toolchainModule is the module the go command downloads when it switches to
+// another toolchain. It has no entry in go.sum and is always kept.
+const toolchainModule = "golang.org/toolchain"
+
+// CachedModule is a module version held by a module cache, with the files
+// of it that were downloaded and extracted.
+type CachedModule struct {
+	// Key identifies the module version inside the layer that holds the
+	// module cache, such as github.com/some/dependency@v1.2.3.
+	Key string
+
+	// Version is the module path and version, such as
+	// github.com/some/dependency@v1.2.3.
+	Version string
+
+	// Size is the size of its files in bytes.
+	Size int64
+
+	// LastUsed is the time of the last build that used the module version.
+	LastUsed time.Time
+
+	cache string
+	files []string
+}
+
+// ListModuleCache lists the module versions held by the module cache at
+// path, which is inside the layer at root. It does not record when they were
+// last used.
+func ListModuleCache(root, path string) ([]CachedModule, error) {
+	prefix, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]string{}
+	err = filepath.WalkDir(path, func(p string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "cache" && entry.IsDir() {
+			return filepath.SkipDir
+		}
+
+		// Extracted modules live in <path>@<version> directories, next to a
+		// <path>@<version>.partial file while they are being extracted.
+		i := strings.LastIndex(rel, "@")
+		if i < 0 {
+			return nil
+		}
+
+		if version, ok := unescapeVersion(rel[:i], strings.TrimSuffix(rel[i+1:], ".partial")); ok {
+			files[version] = append(files[version], p)
+		}
+
+		if entry.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the module cache: %w", err)
+	}
+
+	downloadDir := filepath.Join(path, "cache", "download")
+	err = filepath.WalkDir(downloadDir, func(p string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == downloadDir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if p == filepath.Join(downloadDir, "sumdb") {
+			return filepath.SkipDir
+		}
+
+		if entry.Name() != "@v" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(downloadDir, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range entries {
+			escapedVersion, ok := downloadedVersion(file.Name())
+			if !ok {
+				continue
+			}
+
+			if version, ok := unescapeVersion(filepath.ToSlash(rel), escapedVersion); ok {
+				files[version] = append(files[version], filepath.Join(p, file.Name()))
+			}
+		}
+
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the module cache: %w", err)
+	}
+
+	var modules []CachedModule
+	for version, paths := range files {
+		var size int64
+		for _, p := range paths {
+			s, err := treeSize(p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list the module cache: %w", err)
+			}
+			size += s
+		}
+
+		modules = append(modules, CachedModule{
+			Key:     filepath.ToSlash(filepath.Join(prefix, version)),
+			Version: version,
+			Size:    size,
+			cache:   path,
+			files:   paths,
+		})
+	}
+
+	slices.SortFunc(modules, func(a, b CachedModule) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return modules, nil
+}
+
+// PruneModuleCache removes every module version that has no entry in the
+// given go.sum content from the module cache at path, both from the
+// downloaded files in cache/download and from the extracted module
+// directories. It returns the number of bytes reclaimed.
+func PruneModuleCache(path string, sums GoSum) (int64, error) {
+	keep := map[string]bool{}
+	for _, entry := range sums.Entries {
+		keep[entry.Path+"@"+strings.TrimSuffix(entry.Version, "/go.mod")] = true
+	}
+
+	modules, err := ListModuleCache(path, path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune the module cache: %w", err)
+	}
+
+	var reclaimed int64
+	for _, cached := range modules {
+		if keep[cached.Version] || strings.HasPrefix(cached.Version, toolchainModule+"@") {
+			continue
+		}
+
+		err = cached.remove()
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune the module cache: %w", err)
+		}
+		reclaimed += cached.Size
+	}
+
+	size, err := tidyModuleCache(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune the module cache: %w", err)
+	}
+
+	return reclaimed + size, nil
+}
+
+// EvictModuleCache removes the least recently used module versions until
+// the given modules fit in maxSize bytes. The module versions in keep are
+// never removed. It returns the evicted modules, from the least recently
+// used, and the size of the remaining ones.
+func EvictModuleCache(modules []CachedModule, keep map[string]bool, maxSize int64) ([]CachedModule, int64, error) {
+	var size int64
+	for _, cached := range modules {
+		size += cached.Size
+	}
+
+	candidates := slices.Clone(modules)
+	slices.SortStableFunc(candidates, func(a, b CachedModule) int {
+		return a.LastUsed.Compare(b.LastUsed)
+	})
+
+	var evicted []CachedModule
+	caches := map[string]bool{}
+	for _, cached := range candidates {
+		if size <= maxSize {
+			break
+		}
+
+		if keep[cached.Key] {
+			continue
+		}
+
+		err := cached.remove()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to evict from the module cache: %w", err)
+		}
+
+		size -= cached.Size
+		evicted = append(evicted, cached)
+		caches[cached.cache] = true
+	}
+
+	for cache := range caches {
+		_, err := tidyModuleCache(cache)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to evict from the module cache: %w", err)
+		}
+	}
+
+	return evicted, size, nil
+}
+
+func (m CachedModule) remove() error {
+	for _, path := range m.files {
+		_, err := removeTree(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unescapeVersion returns the module path and version of a directory or file
+// of the module cache, as <path>@<version>. Names that are not escaped
+// module paths and versions are not module versions.
+func unescapeVersion(escapedPath, escapedVersion string) (string, bool) {
+	modulePath, err := module.UnescapePath(escapedPath)
+	if err != nil {
+		return "", false
+	}
+
+	version, err := module.UnescapeVersion(escapedVersion)
+	if err != nil {
+		return "", false
+	}
+
+	return modulePath + "@" + version, true
+}
+
+// tidyModuleCache removes the @v directories of cache/download that no
+// longer hold any version, along with their version list, and the
+// directories left empty. It returns the number of bytes reclaimed.
+func tidyModuleCache(path string) (int64, error) {
+	var reclaimed int64
+	downloadDir := filepath.Join(path, "cache", "download")
+	err := filepath.WalkDir(downloadDir, func(p string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == downloadDir {
+				return filepath.SkipAll
+			}
+			return err
+		}
+
+		if !entry.IsDir() || entry.Name() != "@v" {
+			return nil
+		}
+
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range entries {
+			if _, ok := downloadedVersion(file.Name()); ok {
+				return filepath.SkipDir
+			}
+		}
+
+		size, err := removeTree(p)
+		if err != nil {
+			return err
+		}
+		reclaimed += size
+
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = removeEmptyDirs(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return reclaimed, nil
+}
+
+// downloadedVersion returns the escaped version a file of an @v directory
+// belongs to, such as v1.2.3 for v1.2.3.zip or v1.2.3.ziphash. The version
+// list is not the file of a version.
+func downloadedVersion(name string) (string, bool) {
+	i := -1
+	for _, ext := range []string{".info", ".mod", ".zip", ".lock"} {
+		if j := strings.Index(name, ext); j > 0 && (i < 0 || j < i) {
+			i = j
+		}
+	}
+
+	if i < 0 || strings.HasPrefix(name, "list") {
+		return "", false
+	}
+
+	return name[:i], true
+}
+
+// treeSize returns the size of the files held by a file or directory.
+func treeSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(p string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+
+		return nil
+	})
+
+	return size, err
+}
+
+// moduleLastUsed reads the times at which the module versions of the
+// mod-cache layer were last used, as recorded in its metadata by a previous
+// build.
+func moduleLastUsed(metadata map[string]interface{}) map[string]time.Time {
+	lastUsed := map[string]time.Time{}
+
+	recorded, ok := metadata["last-used"].(map[string]interface{})
+	if !ok {
+		return lastUsed
+	}
+
+	for key, value := range recorded {
+		value, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			lastUsed[key] = t
+		}
+	}
+
+	return lastUsed
+}
+
+// removeTree removes a file or directory, such as a whole module cache, and
+// returns the size of the files it held. The go command makes extracted
+// modules read-only, so directories are made writable first.
+func removeTree(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(p string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return os.Chmod(p, 0755)
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.RemoveAll(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// removeEmptyDirs removes the directories below root that are left empty.
+// Extracted modules are read-only and left as they are.
+func removeEmptyDirs(root string) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.Contains(entry.Name(), "@") {
+			continue
+		}
+
+		dir := filepath.Join(root, entry.Name())
+		err = removeEmptyDirs(dir)
+		if err != nil {
+			return err
+		}
+
+		children, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		if len(children) == 0 {
+			err = os.Remove(dir)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// formatBytes formats a number of bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
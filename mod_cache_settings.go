@@ -0,0 +1,88 @@
+package gomodvendor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ModCacheSettings records what produced the content of the mod-cache layer.
+// A module cache filled by another version of Go, from another proxy or with
+// other flags is not reused.
+type ModCacheSettings struct {
+	GoVersion        string
+	GoProxy          string
+	GoPrivate        string
+	GoFlags          string
+	BuildpackVersion string
+}
+
+type modCacheSetting struct {
+	key   string
+	name  string
+	value string
+}
+
+// fields lists the settings along with their metadata keys and the names
+// used to log a change.
+func (s ModCacheSettings) fields() []modCacheSetting {
+	return []modCacheSetting{
+		{"go-version", "the Go version", s.GoVersion},
+		{"goproxy", "GOPROXY", s.GoProxy},
+		{"goprivate", "GOPRIVATE", s.GoPrivate},
+		{"goflags", "GOFLAGS", s.GoFlags},
+		{"buildpack-version", "the buildpack version", s.BuildpackVersion},
+	}
+}
+
+// combineModCacheSettings merges the settings read in several modules that
+// share the mod-cache layer. A setting that differs between modules records
+// each distinct value, in order, separated by "; ".
+func combineModCacheSettings(all []ModCacheSettings) ModCacheSettings {
+	combine := func(value func(ModCacheSettings) string) string {
+		var values []string
+		for _, settings := range all {
+			if !slices.Contains(values, value(settings)) {
+				values = append(values, value(settings))
+			}
+		}
+
+		return strings.Join(values, "; ")
+	}
+
+	return ModCacheSettings{
+		GoVersion:        combine(func(s ModCacheSettings) string { return s.GoVersion }),
+		GoProxy:          combine(func(s ModCacheSettings) string { return s.GoProxy }),
+		GoPrivate:        combine(func(s ModCacheSettings) string { return s.GoPrivate }),
+		GoFlags:          combine(func(s ModCacheSettings) string { return s.GoFlags }),
+		BuildpackVersion: combine(func(s ModCacheSettings) string { return s.BuildpackVersion }),
+	}
+}
+
+// Metadata returns the settings as layer metadata.
+func (s ModCacheSettings) Metadata() map[string]interface{} {
+	metadata := map[string]interface{}{}
+	for _, field := range s.fields() {
+		metadata[field.key] = field.value
+	}
+
+	return metadata
+}
+
+// Changed compares the settings with those recorded in the metadata of the
+// layer and describes the first one that differs. A layer that records no
+// settings was written by an older version of the buildpack and is kept.
+func (s ModCacheSettings) Changed(metadata map[string]interface{}) (string, bool) {
+	if _, ok := metadata["go-version"]; !ok {
+		return "", false
+	}
+
+	for _, field := range s.fields() {
+		recorded, _ := metadata[field.key].(string)
+		if recorded != field.value {
+			return fmt.Sprintf("%s changed from '%s' to '%s'", field.name, recorded, field.value), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,62 @@
+package gomodvendor_test
+
+import (
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testModCacheSettings(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		settings gomodvendor.ModCacheSettings
+	)
+
+	it.Before(func() {
+		settings = gomodvendor.ModCacheSettings{
+			GoVersion:        "go1.23.4",
+			GoProxy:          "https://proxy.golang.org,direct",
+			GoPrivate:        "example.com/private",
+			GoFlags:          "",
+			BuildpackVersion: "1.2.3",
+		}
+	})
+
+	context("Changed", func() {
+		it("is not changed when the metadata matches", func() {
+			_, changed := settings.Changed(settings.Metadata())
+			Expect(changed).To(BeFalse())
+		})
+
+		it("describes the setting that differs", func() {
+			metadata := settings.Metadata()
+			metadata["goproxy"] = "https://proxy.example.com"
+
+			reason, changed := settings.Changed(metadata)
+			Expect(changed).To(BeTrue())
+			Expect(reason).To(Equal("GOPROXY changed from 'https://proxy.example.com' to 'https://proxy.golang.org,direct'"))
+		})
+
+		it("treats a missing setting as empty", func() {
+			metadata := settings.Metadata()
+			delete(metadata, "buildpack-version")
+
+			reason, changed := settings.Changed(metadata)
+			Expect(changed).To(BeTrue())
+			Expect(reason).To(Equal("the buildpack version changed from '' to '1.2.3'"))
+		})
+
+		context("when the metadata records no settings", func() {
+			it("is not changed", func() {
+				_, changed := settings.Changed(map[string]interface{}{
+					"vendor-digests": map[string]interface{}{},
+				})
+				Expect(changed).To(BeFalse())
+			})
+		})
+	})
+}
@@ -0,0 +1,245 @@
+package gomodvendor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testModCache(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		modCacheDir string
+		sums        gomodvendor.GoSum
+	)
+
+	write := func(path, content string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(modCacheDir, path)), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(modCacheDir, path), []byte(content), 0444)).To(Succeed())
+	}
+
+	it.Before(func() {
+		var err error
+		modCacheDir, err = os.MkdirTemp("", "mod-cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		write("cache/download/github.com/some/dependency/@v/list", "v1.2.2\nv1.2.3\n")
+		write("cache/download/github.com/some/dependency/@v/v1.2.3.mod", "module github.com/some/dependency\n")
+		write("cache/download/github.com/some/dependency/@v/v1.2.3.zip", "new-zip")
+		write("cache/download/github.com/some/dependency/@v/v1.2.2.mod", "old-mod")
+		write("cache/download/github.com/some/dependency/@v/v1.2.2.zip", "old-zip")
+		write("cache/download/github.com/some/dependency/@v/v1.2.2.ziphash", "old-hash")
+		write("cache/download/github.com/some/dependency/@v/v1.2.2.lock", "")
+
+		write("cache/download/github.com/!burnt!sushi/toml/@v/list", "v1.0.0\n")
+		write("cache/download/github.com/!burnt!sushi/toml/@v/v1.0.0.mod", "module github.com/BurntSushi/toml\n")
+
+		write("cache/download/github.com/old/module/@v/list", "v0.1.0\n")
+		write("cache/download/github.com/old/module/@v/v0.1.0.zip", "unused")
+
+		write("cache/download/sumdb/sum.golang.org/latest", "tree")
+
+		write("github.com/some/dependency@v1.2.3/dependency.go", "package dependency\n")
+		write("github.com/some/dependency@v1.2.2/dependency.go", "package old\n")
+		write("github.com/old/module@v0.1.0/module.go", "package module\n")
+		write("golang.org/toolchain@v0.0.1-go1.22.5.linux-amd64/VERSION", "go1.22.5")
+
+		// The go command makes extracted modules read-only.
+		for _, dir := range []string{
+			"github.com/some/dependency@v1.2.3",
+			"github.com/some/dependency@v1.2.2",
+			"github.com/old/module@v0.1.0",
+		} {
+			Expect(os.Chmod(filepath.Join(modCacheDir, dir), 0555)).To(Succeed())
+		}
+
+		sums = gomodvendor.GoSum{
+			Entries: []gomodvendor.SumEntry{
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}, Hash: "h1:some-hash="},
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3/go.mod"}, Hash: "h1:some-hash="},
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/BurntSushi/toml", Version: "v1.0.0/go.mod"}, Hash: "h1:some-hash="},
+			},
+		}
+	})
+
+	it.After(func() {
+		Expect(filepath.WalkDir(modCacheDir, func(path string, entry os.DirEntry, err error) error {
+			if err == nil && entry.IsDir() {
+				return os.Chmod(path, 0755)
+			}
+			return nil
+		})).To(Succeed())
+		Expect(os.RemoveAll(modCacheDir)).To(Succeed())
+	})
+
+	context("ListModuleCache", func() {
+		it("lists the module versions with the size of their files", func() {
+			modules, err := gomodvendor.ListModuleCache(filepath.Dir(modCacheDir), modCacheDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			prefix := filepath.Base(modCacheDir)
+
+			var keys []string
+			sizes := map[string]int64{}
+			for _, module := range modules {
+				keys = append(keys, module.Key)
+				sizes[module.Version] = module.Size
+			}
+
+			Expect(keys).To(Equal([]string{
+				prefix + "/github.com/BurntSushi/toml@v1.0.0",
+				prefix + "/github.com/old/module@v0.1.0",
+				prefix + "/github.com/some/dependency@v1.2.2",
+				prefix + "/github.com/some/dependency@v1.2.3",
+				prefix + "/golang.org/toolchain@v0.0.1-go1.22.5.linux-amd64",
+			}))
+
+			Expect(sizes).To(Equal(map[string]int64{
+				"github.com/BurntSushi/toml@v1.0.0":                34,
+				"github.com/old/module@v0.1.0":                     6 + 15,
+				"github.com/some/dependency@v1.2.2":                7 + 7 + 8 + 12,
+				"github.com/some/dependency@v1.2.3":                34 + 7 + 19,
+				"golang.org/toolchain@v0.0.1-go1.22.5.linux-amd64": 8,
+			}))
+		})
+	})
+
+	context("EvictModuleCache", func() {
+		var modules []gomodvendor.CachedModule
+
+		it.Before(func() {
+			var err error
+			modules, err = gomodvendor.ListModuleCache(modCacheDir, modCacheDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			lastUsed := map[string]time.Time{
+				"github.com/BurntSushi/toml@v1.0.0":                time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+				"github.com/old/module@v0.1.0":                     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				"github.com/some/dependency@v1.2.3":                time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+				"golang.org/toolchain@v0.0.1-go1.22.5.linux-amd64": time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			}
+			for i := range modules {
+				modules[i].LastUsed = lastUsed[modules[i].Key]
+			}
+		})
+
+		it("removes the least recently used module versions until the modules fit", func() {
+			evicted, size, err := gomodvendor.EvictModuleCache(modules, nil, 102)
+			Expect(err).NotTo(HaveOccurred())
+
+			var keys []string
+			for _, module := range evicted {
+				keys = append(keys, module.Key)
+			}
+			Expect(keys).To(Equal([]string{
+				"github.com/some/dependency@v1.2.2",
+				"github.com/old/module@v0.1.0",
+			}))
+			Expect(size).To(Equal(int64(34 + 60 + 8)))
+
+			Expect(filepath.Join(modCacheDir, "github.com/some/dependency@v1.2.2")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/some/dependency/@v/v1.2.2.zip")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/old")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "github.com/old")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "github.com/some/dependency@v1.2.3/dependency.go")).To(BeARegularFile())
+		})
+
+		context("when module versions are kept", func() {
+			it("does not remove them", func() {
+				evicted, size, err := gomodvendor.EvictModuleCache(modules, map[string]bool{
+					"github.com/some/dependency@v1.2.2": true,
+					"github.com/old/module@v0.1.0":      true,
+				}, 115)
+				Expect(err).NotTo(HaveOccurred())
+
+				var keys []string
+				for _, module := range evicted {
+					keys = append(keys, module.Key)
+				}
+				Expect(keys).To(Equal([]string{
+					"golang.org/toolchain@v0.0.1-go1.22.5.linux-amd64",
+					"github.com/BurntSushi/toml@v1.0.0",
+				}))
+				Expect(size).To(Equal(int64(34 + 21 + 60)))
+			})
+		})
+
+		context("when the modules already fit", func() {
+			it("removes nothing", func() {
+				evicted, size, err := gomodvendor.EvictModuleCache(modules, nil, 1<<20)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(evicted).To(BeEmpty())
+				Expect(size).To(Equal(int64(34 + 21 + 34 + 60 + 8)))
+			})
+		})
+	})
+
+	context("PruneModuleCache", func() {
+		it("removes the module versions that go.sum does not refer to", func() {
+			reclaimed, err := gomodvendor.PruneModuleCache(modCacheDir, sums)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/some/dependency/@v/list")).To(BeARegularFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/some/dependency/@v/v1.2.3.mod")).To(BeARegularFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/some/dependency/@v/v1.2.3.zip")).To(BeARegularFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/some/dependency/@v/v1.2.2.mod")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/some/dependency/@v/v1.2.2.zip")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/some/dependency/@v/v1.2.2.ziphash")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/some/dependency/@v/v1.2.2.lock")).NotTo(BeAnExistingFile())
+
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/!burnt!sushi/toml/@v/v1.0.0.mod")).To(BeARegularFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/github.com/old")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "cache/download/sumdb/sum.golang.org/latest")).To(BeARegularFile())
+
+			Expect(filepath.Join(modCacheDir, "github.com/some/dependency@v1.2.3/dependency.go")).To(BeARegularFile())
+			Expect(filepath.Join(modCacheDir, "github.com/some/dependency@v1.2.2")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "github.com/old")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "golang.org/toolchain@v0.0.1-go1.22.5.linux-amd64/VERSION")).To(BeARegularFile())
+
+			// old-mod, old-zip, old-hash, the list and archive of the old
+			// module and the two removed module directories.
+			Expect(reclaimed).To(Equal(int64(7 + 7 + 8 + 7 + 6 + 12 + 15)))
+		})
+
+		context("when the module cache is already pruned", func() {
+			it.Before(func() {
+				_, err := gomodvendor.PruneModuleCache(modCacheDir, sums)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("reclaims nothing", func() {
+				reclaimed, err := gomodvendor.PruneModuleCache(modCacheDir, sums)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reclaimed).To(BeZero())
+			})
+		})
+
+		context("when the module cache has not downloaded anything", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(modCacheDir, "cache"))).To(Succeed())
+			})
+
+			it("prunes the extracted modules", func() {
+				_, err := gomodvendor.PruneModuleCache(modCacheDir, sums)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filepath.Join(modCacheDir, "github.com/old")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the module cache does not exist", func() {
+				it("returns an error", func() {
+					_, err := gomodvendor.PruneModuleCache(filepath.Join(modCacheDir, "missing"), sums)
+					Expect(err).To(MatchError(ContainSubstring("failed to prune the module cache")))
+				})
+			})
+		})
+	})
+}
@@ -1,9 +1,16 @@
 package gomodvendor
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,53 +26,1279 @@ type Executable interface {
 }
 
 type ModVendor struct {
-	executable Executable
-	logs       scribe.Emitter
-	clock      chronos.Clock
+	executable  Executable
+	goModParser ModuleParser
+	logs        scribe.Emitter
+	clock       chronos.Clock
 }
 
-func NewModVendor(executable Executable, logs scribe.Emitter, clock chronos.Clock) ModVendor {
+func NewModVendor(executable Executable, goModParser ModuleParser, logs scribe.Emitter, clock chronos.Clock) ModVendor {
 	return ModVendor{
-		executable: executable,
-		logs:       logs,
-		clock:      clock,
+		executable:  executable,
+		goModParser: goModParser,
+		logs:        logs,
+		clock:       clock,
 	}
 }
 
-func (m ModVendor) ShouldRun(workingDir string) (bool, string, error) {
+func (m ModVendor) ShouldRun(workingDir string, config BuildConfiguration) (bool, string, error) {
 	ok, err := fs.Exists(filepath.Join(workingDir, "vendor"))
 	if err != nil {
 		return false, "", err
 	}
-	if ok {
+	if ok && config.VendorMode == VendorModeDownload {
 		return false, "modules are already vendored", nil
 	}
 
+	if ok && config.Revendor {
+		return true, "", nil
+	}
+
+	if ok {
+		consistent, err := m.checkVendor(workingDir, config)
+		if err != nil {
+			return false, "", err
+		}
+
+		if consistent {
+			return false, "modules are already vendored", nil
+		}
+
+		// go mod vendor replaces the whole vendor directory, even when the
+		// module graph turns out to be empty.
+		return true, "", nil
+	}
+
+	_, isWorkspace, err := findWorkspace(workingDir, config)
+	if err != nil {
+		return false, "", err
+	}
+
+	if isWorkspace {
+		return true, "", nil
+	}
+
+	goModPath := filepath.Join(workingDir, GoModLocation)
+	ok, err = fs.Exists(goModPath)
+	if err != nil {
+		return false, "", err
+	}
+	if !ok {
+		return true, "", nil
+	}
+
+	goMod, err := m.goModParser.Parse(goModPath)
+	if err != nil {
+		return false, "", err
+	}
+
+	// Without any require directive there is nothing to vendor, but go-dist
+	// still provides Go for the buildpacks that follow.
+	if len(goMod.Require) == 0 {
+		return false, "module graph is empty", nil
+	}
+
 	return true, "", nil
 }
 
-func (m ModVendor) Execute(path, workingDir string) error {
+var verifyFailurePattern = regexp.MustCompile(`^(\S+) (v\S+): (.+)$`)
+
+// VerifyCache runs go mod verify against the module cache at the given path.
+// When a module in the cache was modified, the whole cache is removed so that
+// every module is downloaded again when vendoring.
+func (m ModVendor) VerifyCache(path, workingDir string, config BuildConfiguration) error {
+	exists, err := fs.Exists(path)
+	if err != nil {
+		return err
+	}
+
+	if !exists || fs.IsEmptyDir(path) {
+		return nil
+	}
+
+	env := append(os.Environ(), fmt.Sprintf("GOMODCACHE=%s", path))
+	env = append(env, proxyEnv(config)...)
+	if config.DisableWorkspace {
+		env = append(env, "GOWORK=off")
+	}
+
+	m.logs.Process("Verifying the module cache")
+	m.logs.Subprocess("Running 'go mod verify'")
+
+	output := bytes.NewBuffer(nil)
+	duration, err := m.clock.Measure(func() error {
+		return m.executable.Execute(pexec.Execution{
+			Args:   []string{"mod", "verify"},
+			Env:    env,
+			Dir:    workingDir,
+			Stdout: output,
+			Stderr: output,
+		})
+	})
+	if err == nil {
+		m.logs.Action("Completed in %s", duration.Round(time.Millisecond))
+		m.logs.Break()
+		return nil
+	}
+
+	m.logs.Action("Failed after %s", duration.Round(time.Millisecond))
+
+	var failures []string
+	for _, line := range strings.Split(output.String(), "\n") {
+		if verifyFailurePattern.MatchString(line) {
+			failures = append(failures, line)
+		}
+	}
+
+	// Without a module to blame, the failure is not caused by the cache.
+	if len(failures) == 0 {
+		_, _ = io.Copy(m.logs.ActionWriter, output)
+		return err
+	}
+
+	m.logs.Subprocess("The following modules failed verification")
+	for _, failure := range failures {
+		m.logs.Action("%s", failure)
+	}
+
+	m.logs.Subprocess("Removing the module cache to download every module again")
+	_, err = removeTree(path)
+	if err != nil {
+		return fmt.Errorf("failed to remove the module cache: %w", err)
+	}
+
+	m.logs.Break()
+
+	return nil
+}
+
+// PruneCache removes the module versions that none of the modules in the
+// given directories has in its go.sum file (or in go.work.sum and the go.sum
+// files of a workspace) from the module cache at path, which only grows
+// otherwise. It returns the module versions whose content go.sum records, as
+// <path>@<version>, which are those the build resolves: the other entries
+// only record the go.mod file of a version that module graph pruning needs.
+func (m ModVendor) PruneCache(path string, workingDirs []string, config BuildConfiguration) ([]string, error) {
+	exists, err := fs.Exists(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	var sumPaths []string
+	for _, workingDir := range workingDirs {
+		modules, err := m.findModules(workingDir, config)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range modules.sumFiles() {
+			sumPaths = append(sumPaths, filepath.Join(workingDir, name))
+		}
+	}
+
+	var sums GoSum
+	for _, sumPath := range sumPaths {
+		exists, err := fs.Exists(sumPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			continue
+		}
+
+		goSum, err := m.goModParser.ParseGoSum(sumPath)
+		if err != nil {
+			return nil, err
+		}
+
+		sums = sums.Merge(goSum)
+	}
+
+	m.logs.Process("Pruning the module cache")
+
+	reclaimed, err := PruneModuleCache(path, sums)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logs.Subprocess("Reclaimed %s", formatBytes(reclaimed))
+	m.logs.Break()
+
+	var resolved []string
+	for _, entry := range sums.Entries {
+		version := entry.Path + "@" + entry.Version
+		if !strings.HasSuffix(entry.Version, "/go.mod") && !slices.Contains(resolved, version) {
+			resolved = append(resolved, version)
+		}
+	}
+
+	slices.Sort(resolved)
+
+	return resolved, nil
+}
+
+// tidyDiffVersion is the first Go release whose go mod tidy supports -diff.
+var tidyDiffVersion = GoVersion{Major: 1, Minor: 23}
+
+// CheckTidy fails when go mod tidy would change the go.mod or go.sum file of
+// the module, or of any module used by a workspace, and shows the changes it
+// would make. The working directory itself is left untouched.
+func (m ModVendor) CheckTidy(path, workingDir string, config BuildConfiguration) error {
+	// go mod tidy works on a single module, so the workspace is ignored.
+	env := append(os.Environ(), fmt.Sprintf("GOMODCACHE=%s", path), "GOWORK=off")
+	env = append(env, proxyEnv(config)...)
+
+	m.logs.Process("Checking that go.mod and go.sum are tidy")
+
+	version, err := m.goVersion(env, workingDir)
+	if err != nil {
+		return err
+	}
+
+	if version.Compare(tidyDiffVersion) < 0 {
+		return fmt.Errorf("BP_GO_MOD_TIDY_CHECK needs 'go mod tidy -diff', which requires Go %s or later, but Go %s is installed: request a newer Go with BP_GO_VERSION or unset BP_GO_MOD_TIDY_CHECK", tidyDiffVersion, version)
+	}
+
+	modules, err := m.findModules(workingDir, config)
+	if err != nil {
+		return err
+	}
+
+	var untidy []string
+	for _, dir := range modules.dirs {
+		moduleDir := filepath.Join(workingDir, dir)
+
+		// A go.mod file that is generated during the build has nothing to
+		// compare against.
+		exists, err := fs.Exists(filepath.Join(moduleDir, GoModLocation))
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		if modules.workspace {
+			m.logs.Subprocess("Running 'go mod tidy -diff' in '%s'", dir)
+		} else {
+			m.logs.Subprocess("Running 'go mod tidy -diff'")
+		}
+
+		diff := bytes.NewBuffer(nil)
+		duration, err := m.clock.Measure(func() error {
+			return m.executable.Execute(pexec.Execution{
+				Args:   []string{"mod", "tidy", "-diff"},
+				Env:    env,
+				Dir:    moduleDir,
+				Stdout: diff,
+				Stderr: m.logs.ActionWriter,
+			})
+		})
+		if err != nil {
+			// go mod tidy -diff exits with an error when it prints a diff; any
+			// other failure has no diff to show.
+			if diff.Len() == 0 {
+				m.logs.Action("Failed after %s", duration.Round(time.Millisecond))
+				return err
+			}
+
+			m.logs.Action("go.mod and go.sum are not tidy, go mod tidy would make these changes:")
+			for _, line := range strings.Split(strings.TrimRight(diff.String(), "\n"), "\n") {
+				m.logs.Detail("%s", line)
+			}
+
+			untidy = append(untidy, fmt.Sprintf("'%s'", dir))
+			continue
+		}
+
+		m.logs.Action("Completed in %s", duration.Round(time.Millisecond))
+	}
+
+	m.logs.Break()
+
+	if len(untidy) > 0 {
+		if !modules.workspace {
+			return errors.New("go.mod and go.sum are not tidy: run 'go mod tidy' and commit the result")
+		}
+
+		return fmt.Errorf("go.mod and go.sum are not tidy in %s: run 'go mod tidy' and commit the result", strings.Join(untidy, ", "))
+	}
+
+	return nil
+}
+
+// goVersion returns the version of the installed go command.
+func (m ModVendor) goVersion(env []string, workingDir string) (GoVersion, error) {
+	buffer := bytes.NewBuffer(nil)
+	err := m.executable.Execute(pexec.Execution{
+		Args:   []string{"env", "GOVERSION"},
+		Env:    env,
+		Dir:    workingDir,
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return GoVersion{}, fmt.Errorf("failed to determine the installed go version: %w: %s", err, strings.TrimSpace(buffer.String()))
+	}
+
+	version, ok, err := ParseToolchain(strings.TrimSpace(buffer.String()))
+	if err != nil || !ok {
+		return GoVersion{}, fmt.Errorf("failed to determine the installed go version: unexpected output %q", strings.TrimSpace(buffer.String()))
+	}
+
+	return version, nil
+}
+
+// ModCacheSettings reads the version of Go and the settings of the go command
+// that decide the content of the module cache, in the environment that
+// Execute runs the go command with. The buildpack version is left to the
+// caller.
+func (m ModVendor) ModCacheSettings(workingDir string, config BuildConfiguration) (ModCacheSettings, error) {
+	env := append(os.Environ(), proxyEnv(config)...)
+	if config.DisableWorkspace {
+		env = append(env, "GOWORK=off")
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err := m.executable.Execute(pexec.Execution{
+		Args:   []string{"env", "-json", "GOVERSION", "GOPROXY", "GOPRIVATE", "GOFLAGS"},
+		Env:    env,
+		Dir:    workingDir,
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return ModCacheSettings{}, fmt.Errorf("failed to read the go environment: %w: %s", err, strings.TrimSpace(buffer.String()))
+	}
+
+	var goEnv map[string]string
+	err = json.Unmarshal(buffer.Bytes(), &goEnv)
+	if err != nil {
+		return ModCacheSettings{}, fmt.Errorf("failed to read the go environment: unexpected output %q", strings.TrimSpace(buffer.String()))
+	}
+
+	return ModCacheSettings{
+		GoVersion: goEnv["GOVERSION"],
+		GoProxy:   goEnv["GOPROXY"],
+		GoPrivate: goEnv["GOPRIVATE"],
+		GoFlags:   goEnv["GOFLAGS"],
+	}, nil
+}
+
+// LocalReplacements validates the directory replacements of the module (or
+// of the workspace and the modules it uses) in the given directory and
+// returns them. rootDir is the working directory of the app, outside of which
+// no replacement directory is available.
+func (m ModVendor) LocalReplacements(rootDir, workingDir string, config BuildConfiguration) ([]LocalReplacement, error) {
+	modules, err := m.findModules(workingDir, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var replacements []LocalReplacement
+	if modules.workspace {
+		replacements, err = FindLocalReplacements(rootDir, workingDir, modules.goWork.Replace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dir := range modules.dirs {
+		dir = filepath.Join(workingDir, dir)
+		goModPath := filepath.Join(dir, GoModLocation)
+		exists, err := fs.Exists(goModPath)
+		if err != nil {
+			return nil, err
+		}
+
+		// A module being migrated from a legacy dependency manager has no
+		// go.mod file yet.
+		if !exists {
+			continue
+		}
+
+		goMod, err := m.goModParser.Parse(goModPath)
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := FindLocalReplacements(rootDir, dir, goMod.Replace)
+		if err != nil {
+			return nil, err
+		}
+
+		replacements = append(replacements, found...)
+	}
+
+	return replacements, nil
+}
+
+// ToolModules returns the modules that provide the tools declared by the
+// module (or by the modules of the workspace) in the given directory, and no
+// package that its own packages import. The vendor directory at vendorPath is
+// read when it has a vendor/modules.txt file, and the module cache at path
+// otherwise.
+func (m ModVendor) ToolModules(path, vendorPath, workingDir string, config BuildConfiguration) ([]string, error) {
+	goMods, err := m.parseModules(workingDir, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// A tool provided by another module of the workspace is built from
+	// source rather than vendored.
+	workspace := map[string]bool{}
+	for _, goMod := range goMods {
+		workspace[goMod.Module.Path] = true
+	}
+
+	var modules []string
+	for _, goMod := range goMods {
+		for _, module := range goMod.ToolModules(workspace) {
+			if !slices.Contains(modules, module) {
+				modules = append(modules, module)
+			}
+		}
+	}
+
+	if len(modules) == 0 {
+		return nil, nil
+	}
+
+	runtime, err := m.runtimeModules(path, vendorPath, workingDir, goMods, config)
+	if err != nil {
+		return nil, err
+	}
+
+	modules = slices.DeleteFunc(modules, func(module string) bool {
+		return slices.Contains(runtime, module)
+	})
+
+	slices.Sort(modules)
+
+	return modules, nil
+}
+
+// runtimeModules returns the modules that provide the packages imported by
+// the packages of the given modules, directly or through their dependencies,
+// leaving out those only needed by tools or tests. Like go mod vendor, it
+// follows the imports of every platform and build tag, rather than only those
+// of the platform that the build runs on.
+func (m ModVendor) runtimeModules(path, vendorPath, workingDir string, goMods []GoMod, config BuildConfiguration) ([]string, error) {
+	var locations []moduleLocation
+	for _, goMod := range goMods {
+		locations = append(locations, moduleLocation{module: goMod.Module.Path, dir: filepath.Dir(goMod.Path)})
+	}
+
+	var vendored bool
+	modulesTxtPath := filepath.Join(vendorPath, "modules.txt")
+	if vendorPath != "" {
+		var err error
+		vendored, err = fs.Exists(modulesTxtPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	packages := map[string]moduleLocation{}
+	if vendored {
+		modulesTxt, err := m.goModParser.ParseModulesTxt(modulesTxtPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, module := range modulesTxt.Modules {
+			for _, pkg := range module.Packages {
+				packages[pkg] = moduleLocation{module: module.Path, dir: filepath.Join(vendorPath, filepath.FromSlash(pkg))}
+			}
+		}
+	} else {
+		downloaded, err := m.moduleDirs(path, workingDir, config)
+		if err != nil {
+			return nil, err
+		}
+
+		locations = append(locations, downloaded...)
+	}
+
+	locate := func(pkg string) (moduleLocation, bool) {
+		if location, ok := packages[pkg]; ok {
+			return location, true
+		}
+
+		return locatePackage(pkg, locations)
+	}
+
+	var roots []string
+	for _, goMod := range goMods {
+		roots = append(roots, filepath.Dir(goMod.Path))
+	}
+
+	return reachableModules(roots, locate)
+}
+
+// moduleDirs lists the directories of the modules in the build list that the
+// module cache at path holds.
+func (m ModVendor) moduleDirs(path, workingDir string, config BuildConfiguration) ([]moduleLocation, error) {
+	env := append(os.Environ(), fmt.Sprintf("GOMODCACHE=%s", path))
+	env = append(env, proxyEnv(config)...)
+	if config.DisableWorkspace {
+		env = append(env, "GOWORK=off")
+	}
+
+	modules, err := m.findModules(workingDir, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// A vendor directory committed to the app is not read, as the go command
+	// cannot list the build list from it. -mod cannot be set in a workspace,
+	// which never uses a vendor directory by default.
+	args := []string{"list", "-m", "-f", "{{.Path}}\t{{.Dir}}", "all"}
+	if !modules.workspace {
+		args = []string{"list", "-mod=mod", "-m", "-f", "{{.Path}}\t{{.Dir}}", "all"}
+	}
+
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err = m.executable.Execute(pexec.Execution{
+		Args:   args,
+		Env:    env,
+		Dir:    workingDir,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the modules: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var locations []moduleLocation
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		module, dir, ok := strings.Cut(line, "\t")
+		if !ok || dir == "" {
+			continue
+		}
+
+		locations = append(locations, moduleLocation{module: module, dir: dir})
+	}
+
+	return locations, nil
+}
+
+// parseModules parses the go.mod file in the given directory, or those of
+// the modules used by the workspace in it. Missing go.mod files are skipped.
+func (m ModVendor) parseModules(workingDir string, config BuildConfiguration) ([]GoMod, error) {
+	modules, err := m.findModules(workingDir, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var goMods []GoMod
+	for _, dir := range modules.dirs {
+		goModPath := filepath.Join(workingDir, dir, GoModLocation)
+		exists, err := fs.Exists(goModPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			continue
+		}
+
+		goMod, err := m.goModParser.Parse(goModPath)
+		if err != nil {
+			return nil, err
+		}
+
+		goMods = append(goMods, goMod)
+	}
+
+	return goMods, nil
+}
+
+// moduleSet holds the module in a working directory, or the modules used by
+// the workspace in it.
+type moduleSet struct {
+	workspace bool
+	goWork    GoWork
+
+	// dirs are the directories of the modules, relative to the working
+	// directory.
+	dirs []string
+}
+
+// findModules returns the module in the given directory, or the modules
+// used by the workspace in it.
+func (m ModVendor) findModules(workingDir string, config BuildConfiguration) (moduleSet, error) {
+	goWorkPath, isWorkspace, err := findWorkspace(workingDir, config)
+	if err != nil {
+		return moduleSet{}, err
+	}
+
+	if !isWorkspace {
+		return moduleSet{dirs: []string{"."}}, nil
+	}
+
+	goWork, err := m.goModParser.ParseWork(goWorkPath)
+	if err != nil {
+		return moduleSet{}, err
+	}
+
+	modules := moduleSet{workspace: true, goWork: goWork}
+	for _, use := range goWork.Use {
+		modules.dirs = append(modules.dirs, filepath.Clean(use.Path))
+	}
+
+	return modules, nil
+}
+
+// files returns the module files that the vendor directory is generated
+// from, relative to the working directory: the go.mod and go.sum files of
+// every module, along with go.work and go.work.sum for a workspace.
+func (s moduleSet) files() []string {
+	var files []string
+	if s.workspace {
+		files = append(files, GoWorkLocation)
+	}
+
+	for _, dir := range s.dirs {
+		files = append(files, filepath.ToSlash(filepath.Join(dir, GoModLocation)))
+	}
+
+	return append(files, s.sumFiles()...)
+}
+
+// sumFiles returns the checksum files of the modules, relative to the
+// working directory.
+func (s moduleSet) sumFiles() []string {
+	var files []string
+	if s.workspace {
+		files = append(files, "go.work.sum")
+	}
+
+	for _, dir := range s.dirs {
+		files = append(files, filepath.ToSlash(filepath.Join(dir, "go.sum")))
+	}
+
+	return files
+}
+
+// VendorKey identifies the vendor directory that go mod vendor would write
+// for the module in the given directory, from its module files, the packages
+// its Go files import, the installed version of Go and the settings that
+// change the result. A new import of a package from a module that is already
+// required changes the key, although the module files stay the same. It is
+// empty when there is no go.mod or go.work file to derive it from.
+func (m ModVendor) VendorKey(workingDir string, config BuildConfiguration) (string, error) {
+	modules, err := m.findModules(workingDir, config)
+	if err != nil {
+		return "", err
+	}
+
+	if !modules.workspace {
+		exists, err := fs.Exists(filepath.Join(workingDir, GoModLocation))
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return "", nil
+		}
+	}
+
+	digest, err := ComputeInputsDigest(workingDir, modules.files()...)
+	if err != nil {
+		return "", err
+	}
+
+	// go mod vendor also vendors the packages that the tests of the module
+	// import.
+	var imports []string
+	for _, dir := range modules.dirs {
+		found, err := moduleImports(filepath.Join(workingDir, dir), true)
+		if err != nil {
+			return "", err
+		}
+
+		imports = append(imports, found...)
+	}
+
+	env := os.Environ()
+	if config.DisableWorkspace {
+		env = append(env, "GOWORK=off")
+	}
+
+	version, err := m.goVersion(env, workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\nimports=%s\ngo%s\nexclude-tools=%t\n", digest, strings.Join(imports, ","), version, config.ExcludeTools)))
+
+	return fmt.Sprintf("%x", key), nil
+}
+
+// VendorDigest computes the digest of the vendor directory and of the module
+// files it is generated from. The inputs also cover vendor/modules.txt, as a
+// new import of a package from a required module changes the vendor
+// directory without changing the module files. It is not ok when there is no
+// vendor directory written by go mod vendor.
+func (m ModVendor) VendorDigest(workingDir string, config BuildConfiguration) (VendorDigest, bool, error) {
+	vendorDir := filepath.Join(workingDir, "vendor")
+	exists, err := fs.Exists(filepath.Join(vendorDir, "modules.txt"))
+	if err != nil {
+		return VendorDigest{}, false, err
+	}
+
+	if !exists {
+		return VendorDigest{}, false, nil
+	}
+
+	modulesTxt, err := m.goModParser.ParseModulesTxt(filepath.Join(vendorDir, "modules.txt"))
+	if err != nil {
+		return VendorDigest{}, false, err
+	}
+
+	digest, err := ComputeVendorDigest(vendorDir, modulesTxt)
+	if err != nil {
+		return VendorDigest{}, false, err
+	}
+
+	modules, err := m.findModules(workingDir, config)
+	if err != nil {
+		return VendorDigest{}, false, err
+	}
+
+	digest.Inputs, err = ComputeInputsDigest(workingDir, append(modules.files(), "vendor/modules.txt")...)
+	if err != nil {
+		return VendorDigest{}, false, err
+	}
+
+	return digest, true, nil
+}
+
+// checkVendor reports whether the existing vendor directory was written by go
+// mod vendor for the current go.mod. An inconsistent vendor directory fails
+// the build unless it is configured to be regenerated.
+func (m ModVendor) checkVendor(workingDir string, config BuildConfiguration) (bool, error) {
+	// Without modules.txt, which includes an empty vendor directory, go build
+	// would reject the vendor directory.
+	modulesTxtPath := filepath.Join(workingDir, "vendor", "modules.txt")
+	exists, err := fs.Exists(modulesTxtPath)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists {
+		m.logs.Process("Found a vendor directory without vendor/modules.txt, vendoring again")
+		m.logs.Break()
+		return false, nil
+	}
+
+	// The modules.txt of a workspace merges the requirements of every module
+	// it uses, so it is trusted as is.
+	_, isWorkspace, err := findWorkspace(workingDir, config)
+	if err != nil {
+		return false, err
+	}
+
+	goModPath := filepath.Join(workingDir, GoModLocation)
+	exists, err = fs.Exists(goModPath)
+	if err != nil {
+		return false, err
+	}
+
+	if isWorkspace || !exists {
+		return true, nil
+	}
+
+	goMod, err := m.goModParser.Parse(goModPath)
+	if err != nil {
+		return false, err
+	}
+
+	modulesTxt, err := m.goModParser.ParseModulesTxt(modulesTxtPath)
+	if err != nil {
+		return false, err
+	}
+
+	diff := modulesTxt.Diff(goMod)
+	if len(diff) == 0 {
+		return true, nil
+	}
+
+	if config.VendorMismatch == VendorMismatchFail {
+		return false, fmt.Errorf("vendor/modules.txt is inconsistent with go.mod:\n  %s\n\nrun 'go mod vendor' and commit the result, or set BP_GO_MOD_VENDOR_MISMATCH=%s to vendor again during the build", strings.Join(diff, "\n  "), VendorMismatchRevendor)
+	}
+
+	m.logs.Process("vendor/modules.txt is inconsistent with go.mod, vendoring again")
+	for _, line := range diff {
+		m.logs.Subprocess("%s", line)
+	}
+	m.logs.Break()
+
+	return false, nil
+}
+
+func (m ModVendor) Execute(path, vendorPath, workingDir string, config BuildConfiguration) error {
 	args := []string{"mod", "vendor"}
+	env := append(os.Environ(), fmt.Sprintf("GOMODCACHE=%s", path))
+	env = append(env, proxyEnv(config)...)
+
+	modules, err := m.findModules(workingDir, config)
+	if err != nil {
+		return err
+	}
+
+	if modules.workspace {
+		args = []string{"work", "vendor"}
+	}
+
+	// A vendor directory outside of the module is written with -o. The go
+	// command only reads the vendor directory of the module root, so it is
+	// left to the buildpacks that compile the app to make it available there.
+	if vendorPath != filepath.Join(workingDir, "vendor") {
+		args = append(args, "-o", vendorPath)
+
+		err = os.MkdirAll(filepath.Dir(vendorPath), os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+
+	// go mod download fills the module cache with every module of the build
+	// list, including those of a workspace.
+	if config.VendorMode == VendorModeDownload {
+		args = []string{"mod", "download"}
+	}
+
+	if config.DisableWorkspace {
+		env = append(env, "GOWORK=off")
+	}
 
 	m.logs.Process("Executing build process")
+
+	if !modules.workspace && config.MigrateLegacy {
+		err = m.migrate(workingDir, config, env)
+		if err != nil {
+			return err
+		}
+	}
+
+	// In strict mode, the go command must neither resolve a module that is
+	// missing from go.mod nor add an entry to go.sum, which would mean
+	// contacting the checksum database during the build. go mod vendor and go
+	// mod download do not accept -mod, so go.sum is checked beforehand and the
+	// module files are restored if the go command changes them anyway.
+	var files moduleFiles
+	if config.Strict {
+		files, err = m.checkGoSum(workingDir, modules, "BP_GO_MOD_STRICT")
+		if err != nil {
+			return err
+		}
+	} else if len(config.ModuleProxies) > 0 {
+		// The checksum database is not reachable from a build that downloads
+		// modules from bindings, so go.sum must hold every checksum.
+		_, err = m.checkGoSum(workingDir, modules, fmt.Sprintf("the %s binding", ModuleCacheBindingType))
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.VendorMode == VendorModeDownload {
+		err = m.run(args, env, workingDir)
+	} else {
+		err = m.vendorWithoutTools(args, env, vendorPath, workingDir, config)
+	}
+
+	if config.Strict {
+		changed, restoreErr := files.restore()
+		if restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+
+		if err == nil && len(changed) > 0 {
+			err = fmt.Errorf("'go %s' changed %s, which is not allowed with BP_GO_MOD_STRICT", strings.Join(args, " "), strings.Join(changed, ", "))
+		}
+	}
+
+	var missing missingModulesError
+	if len(config.ModuleProxies) > 0 && errors.As(err, &missing) {
+		return fmt.Errorf("%w\n\nadd them to the %s binding", err, ModuleCacheBindingType)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	m.logs.Break()
+
+	return nil
+}
+
+// checkGoSum fails when go.sum lacks an entry needed by one of the required
+// modules, listing every missing entry. It returns the module files the go
+// command must not change.
+func (m ModVendor) checkGoSum(workingDir string, modules moduleSet, requiredBy string) (moduleFiles, error) {
+	m.logs.Subprocess("Checking that go.sum has an entry for every required module")
+
+	var (
+		goMods    []GoMod
+		paths     []string
+		sumPaths  []string
+		workspace = map[string]bool{}
+	)
+
+	for _, name := range modules.files() {
+		paths = append(paths, filepath.Join(workingDir, name))
+	}
+
+	for _, name := range modules.sumFiles() {
+		sumPaths = append(sumPaths, filepath.Join(workingDir, name))
+	}
+
+	for _, dir := range modules.dirs {
+		goMod, err := m.goModParser.Parse(filepath.Join(workingDir, dir, GoModLocation))
+		if err != nil {
+			return nil, err
+		}
+
+		goMods = append(goMods, goMod)
+		if modules.workspace {
+			workspace[goMod.Module.Path] = true
+		}
+	}
+
+	var sums GoSum
+	for _, path := range sumPaths {
+		exists, err := fs.Exists(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			continue
+		}
+
+		goSum, err := m.goModParser.ParseGoSum(path)
+		if err != nil {
+			return nil, err
+		}
+
+		sums = sums.Merge(goSum)
+	}
+
+	var missing []string
+	for _, goMod := range goMods {
+		for _, entry := range sums.Missing(goMod, modules.goWork.Replace, workspace) {
+			if !slices.Contains(missing, entry) {
+				missing = append(missing, entry)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("go.sum is missing %d entries required by %s:\n  %s\n\nrun 'go mod tidy' and commit go.sum", len(missing), requiredBy, strings.Join(missing, "\n  "))
+	}
+
+	return snapshotModuleFiles(paths)
+}
+
+// moduleFiles holds the content of go.mod, go.sum and the workspace files, so
+// that a change made by the go command can be detected and undone. A nil
+// content stands for a missing file.
+type moduleFiles map[string][]byte
+
+func snapshotModuleFiles(paths []string) (moduleFiles, error) {
+	files := moduleFiles{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		files[path] = content
+	}
+
+	return files, nil
+}
+
+// restore puts back every file that changed since the snapshot and returns
+// their names.
+func (f moduleFiles) restore() ([]string, error) {
+	var changed []string
+	for path, content := range f {
+		current, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		if bytes.Equal(current, content) && (current == nil) == (content == nil) {
+			continue
+		}
+
+		changed = append(changed, filepath.Base(path))
+
+		if content == nil {
+			err = os.Remove(path)
+		} else {
+			err = os.WriteFile(path, content, 0644)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+	}
+
+	slices.Sort(changed)
+
+	return changed, nil
+}
+
+// vendorWithoutTools vendors the module. When tools are excluded, the tool
+// directives are dropped from the go.mod files while vendoring and put back
+// afterwards, so that go mod vendor leaves out the packages they need.
+func (m ModVendor) vendorWithoutTools(args, env []string, vendorDir, workingDir string, config BuildConfiguration) error {
+	if !config.ExcludeTools {
+		return m.vendor(args, env, vendorDir, workingDir)
+	}
+
+	goMods, err := m.parseModules(workingDir, config)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, goMod := range goMods {
+		if len(goMod.Tool) > 0 {
+			paths = append(paths, goMod.Path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return m.vendor(args, env, vendorDir, workingDir)
+	}
+
+	files, err := snapshotModuleFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	m.logs.Subprocess("Excluding tools from the vendor directory")
+	for _, goMod := range goMods {
+		if len(goMod.Tool) == 0 {
+			continue
+		}
+
+		edit := []string{"mod", "edit"}
+		for _, tool := range goMod.Tool {
+			m.logs.Action("%s", tool.Path)
+			edit = append(edit, fmt.Sprintf("-droptool=%s", tool.Path))
+		}
+
+		err = m.run(edit, env, filepath.Dir(goMod.Path))
+		if err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = m.vendor(args, env, vendorDir, workingDir)
+	}
+
+	_, restoreErr := files.restore()
+	if restoreErr != nil {
+		return errors.Join(err, restoreErr)
+	}
+
+	return err
+}
+
+// vendor runs go mod vendor (or go work vendor). An existing vendor directory
+// is moved aside first and put back if vendoring fails, so that it is never
+// left partially written.
+func (m ModVendor) vendor(args, env []string, vendorDir, workingDir string) error {
+	exists, err := fs.Exists(vendorDir)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return m.run(args, env, workingDir)
+	}
+
+	previous, previousErr := m.readModulesTxt(vendorDir)
+
+	// The backup stays next to the vendor directory so that it can be renamed
+	// rather than copied. The go command ignores directories starting with a
+	// dot.
+	backupDir, err := os.MkdirTemp(filepath.Dir(vendorDir), ".vendor-")
+	if err != nil {
+		return fmt.Errorf("failed to move the vendor directory aside: %w", err)
+	}
+	defer os.RemoveAll(backupDir)
+
+	backup := filepath.Join(backupDir, "vendor")
+	err = os.Rename(vendorDir, backup)
+	if err != nil {
+		return fmt.Errorf("failed to move the vendor directory aside: %w", err)
+	}
+
+	err = m.run(args, env, workingDir)
+	if err != nil {
+		restoreErr := os.RemoveAll(vendorDir)
+		if restoreErr == nil {
+			restoreErr = os.Rename(backup, vendorDir)
+		}
+
+		if restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to restore the vendor directory: %w", restoreErr))
+		}
+
+		m.logs.Action("Restored the previous vendor directory")
+		return err
+	}
+
+	if previousErr != nil {
+		m.logs.Subprocess("Could not compare with the previous vendor directory: %s", previousErr)
+		return nil
+	}
+
+	current, err := m.readModulesTxt(vendorDir)
+	if err != nil {
+		m.logs.Subprocess("Could not compare with the previous vendor directory: %s", err)
+		return nil
+	}
+
+	changes := current.Compare(previous)
+	if changes.Empty() {
+		m.logs.Subprocess("No modules changed compared to the previous vendor directory")
+		return nil
+	}
+
+	m.logs.Subprocess("Changes compared to the previous vendor directory")
+	for _, module := range changes.Added {
+		m.logs.Action("Added %s", module)
+	}
+	for _, module := range changes.Removed {
+		m.logs.Action("Removed %s", module)
+	}
+	for _, change := range changes.Changed {
+		m.logs.Action("Changed %s %s -> %s", change.New.Path, change.Old.Resolved(), change.New.Resolved())
+	}
+
+	return nil
+}
+
+// readModulesTxt parses vendor/modules.txt, treating a missing file as a
+// vendor directory without modules.
+func (m ModVendor) readModulesTxt(vendorDir string) (ModulesTxt, error) {
+	path := filepath.Join(vendorDir, "modules.txt")
+	exists, err := fs.Exists(path)
+	if err != nil {
+		return ModulesTxt{}, err
+	}
+
+	if !exists {
+		return ModulesTxt{Path: path}, nil
+	}
+
+	return m.goModParser.ParseModulesTxt(path)
+}
+
+// migrate generates a go.mod file from the lock file of a legacy dependency
+// manager, pinning every locked project at its recorded version.
+func (m ModVendor) migrate(workingDir string, config BuildConfiguration, env []string) error {
+	exists, err := fs.Exists(filepath.Join(workingDir, GoModLocation))
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	legacy, found, err := FindLegacyManifest(workingDir)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return nil
+	}
+
+	projects, err := legacy.Projects(workingDir)
+	if err != nil {
+		return err
+	}
+
+	modulePath := config.MigrateModulePath
+	if modulePath == "" {
+		modulePath, err = legacy.ModulePath(workingDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	// go mod init cannot infer the module path outside of GOPATH, so the
+	// migration fails before running anything.
+	if modulePath == "" {
+		return fmt.Errorf("failed to migrate from %s: the module path of the app is not recorded in %s, set BP_GO_MOD_MIGRATE_MODULE to the module path to pass to go mod init", legacy.Tool, legacy.Manifest)
+	}
+
+	m.logs.Subprocess("Migrating from %s using %s", legacy.Tool, legacy.LockFile)
+
+	err = m.run([]string{"mod", "init", modulePath}, env, workingDir)
+	if err != nil {
+		return err
+	}
+
+	if len(projects) == 0 {
+		return nil
+	}
+
+	args := []string{"get"}
+	for _, project := range projects {
+		args = append(args, project.Query())
+	}
+
+	return m.run(args, env, workingDir)
+}
+
+func (m ModVendor) run(args, env []string, workingDir string) error {
 	m.logs.Subprocess("Running 'go %s'", strings.Join(args, " "))
 
+	// The output is kept to tell which modules could not be downloaded
+	// when GOPROXY ends with off.
+	output := bytes.NewBuffer(nil)
 	duration, err := m.clock.Measure(func() error {
 		return m.executable.Execute(pexec.Execution{
 			Args:   args,
-			Env:    append(os.Environ(), fmt.Sprintf("GOMODCACHE=%s", path)),
+			Env:    env,
 			Dir:    workingDir,
-			Stdout: m.logs.ActionWriter,
-			Stderr: m.logs.ActionWriter,
+			Stdout: io.MultiWriter(m.logs.ActionWriter, output),
+			Stderr: io.MultiWriter(m.logs.ActionWriter, output),
 		})
 	})
 	if err != nil {
 		m.logs.Action("Failed after %s", duration.Round(time.Millisecond))
+
+		if packages := missingSumPackages(output.String()); len(packages) > 0 {
+			return fmt.Errorf("go.sum is missing the checksums of the modules that provide these packages:\n  %s\n\nrun 'go mod tidy' and commit go.sum: %w", strings.Join(packages, "\n  "), err)
+		}
+
+		if missing := missingModules(output.String()); len(missing) > 0 {
+			return missingModulesError{args: args, modules: missing, err: err}
+		}
+
 		return err
 	}
 
 	m.logs.Action("Completed in %s", duration.Round(time.Millisecond))
-	m.logs.Break()
 
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,6 +27,7 @@ func testModVendor(t *testing.T, context spec.G, it spec.S) {
 		workingDir  string
 		environment []string
 		executable  *fakes.Executable
+		goModParser *fakes.ModuleParser
 		logs        *bytes.Buffer
 
 		modVendor gomodvendor.ModVendor
@@ -39,6 +41,13 @@ func testModVendor(t *testing.T, context spec.G, it spec.S) {
 		environment = os.Environ()
 		executable = &fakes.Executable{}
 
+		goModParser = &fakes.ModuleParser{}
+		goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{
+			Require: []gomodvendor.Require{
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}},
+			},
+		}
+
 		logs = bytes.NewBuffer(nil)
 
 		now := time.Now()
@@ -54,7 +63,7 @@ func testModVendor(t *testing.T, context spec.G, it spec.S) {
 			return t
 		})
 
-		modVendor = gomodvendor.NewModVendor(executable, scribe.NewEmitter(logs), clock)
+		modVendor = gomodvendor.NewModVendor(executable, goModParser, scribe.NewEmitter(logs), clock)
 	})
 
 	it.After(func() {
@@ -62,16 +71,147 @@ func testModVendor(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	context("ShouldRun", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), nil, os.ModePerm)).To(Succeed())
+		})
+
+		it("returns true", func() {
+			ok, reason, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(reason).To(BeEmpty())
+
+			Expect(goModParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.mod")))
+		})
+
+		context("when the module has no dependencies", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{}
+			})
+
+			it("returns false", func() {
+				ok, reason, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+				Expect(reason).To(Equal("module graph is empty"))
+			})
+
+			context("when the working directory is a workspace", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+				})
+
+				it("returns true", func() {
+					ok, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeTrue())
+					Expect(goModParser.ParseCall.CallCount).To(Equal(0))
+				})
+			})
+		})
+
 		context("when there is a vendor directory present", func() {
 			it.Before(func() {
 				Expect(os.Mkdir(filepath.Join(workingDir, "vendor"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "modules.txt"), nil, os.ModePerm)).To(Succeed())
+
+				goModParser.ParseModulesTxtCall.Returns.ModulesTxt = gomodvendor.ModulesTxt{
+					Modules: []gomodvendor.VendoredModule{
+						{
+							ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"},
+							Explicit:      true,
+						},
+					},
+				}
 			})
 
 			it("returns false", func() {
-				ok, reason, err := modVendor.ShouldRun(workingDir)
+				ok, reason, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(ok).To(BeFalse())
 				Expect(reason).To(Equal("modules are already vendored"))
+
+				Expect(goModParser.ParseModulesTxtCall.Receives.Path).To(Equal(filepath.Join(workingDir, "vendor", "modules.txt")))
+			})
+
+			context("when the vendor directory has no modules.txt", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(workingDir, "vendor", "modules.txt"))).To(Succeed())
+				})
+
+				it("returns true", func() {
+					ok, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeTrue())
+
+					Expect(logs.String()).To(ContainSubstring("Found a vendor directory without vendor/modules.txt, vendoring again"))
+				})
+			})
+
+			context("when modules.txt is inconsistent with go.mod", func() {
+				it.Before(func() {
+					goModParser.ParseModulesTxtCall.Returns.ModulesTxt.Modules[0].Version = "v1.2.0"
+				})
+
+				it("returns an error listing the modules", func() {
+					_, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{VendorMismatch: "fail"})
+					Expect(err).To(MatchError(ContainSubstring("vendor/modules.txt is inconsistent with go.mod:\n  github.com/some/dependency: go.mod requires v1.2.3, but vendor/modules.txt has v1.2.0\n")))
+					Expect(err).To(MatchError(ContainSubstring("set BP_GO_MOD_VENDOR_MISMATCH=revendor")))
+				})
+
+				context("when it is configured to vendor again, as by default", func() {
+					it("returns true", func() {
+						ok, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{VendorMismatch: "revendor"})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(ok).To(BeTrue())
+
+						Expect(logs.String()).To(ContainSubstring("  vendor/modules.txt is inconsistent with go.mod, vendoring again"))
+						Expect(logs.String()).To(ContainSubstring("    github.com/some/dependency: go.mod requires v1.2.3, but vendor/modules.txt has v1.2.0"))
+					})
+				})
+			})
+
+			context("when the working directory is a workspace", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+				})
+
+				it("does not compare modules.txt with go.mod", func() {
+					ok, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeFalse())
+					Expect(goModParser.ParseModulesTxtCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when the vendor mode is download", func() {
+				it("returns false without checking modules.txt", func() {
+					ok, reason, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{VendorMode: "download"})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeFalse())
+					Expect(reason).To(Equal("modules are already vendored"))
+					Expect(goModParser.ParseModulesTxtCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when re-vendoring is forced", func() {
+				it("returns true without checking modules.txt", func() {
+					ok, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{Revendor: true})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(ok).To(BeTrue())
+					Expect(goModParser.ParseModulesTxtCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when modules.txt cannot be parsed", func() {
+				it.Before(func() {
+					goModParser.ParseModulesTxtCall.Returns.Error = errors.New("failed to parse modules.txt")
+				})
+
+				it("returns an error", func() {
+					_, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to parse modules.txt"))
+				})
 			})
 		})
 
@@ -86,57 +226,1615 @@ func testModVendor(t *testing.T, context spec.G, it spec.S) {
 				})
 
 				it("returns an error", func() {
-					_, _, err := modVendor.ShouldRun(workingDir)
+					_, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
 					Expect(err).To(MatchError(ContainSubstring("permission denied")))
 				})
 			})
+
+			context("the go.mod cannot be parsed", func() {
+				it.Before(func() {
+					goModParser.ParseCall.Returns.Error = errors.New("failed to parse go.mod")
+				})
+
+				it("returns an error", func() {
+					_, _, err := modVendor.ShouldRun(workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to parse go.mod"))
+				})
+			})
 		})
 	})
 
-	context("Execute", func() {
+	context("LocalReplacements", func() {
 		it.Before(func() {
-			executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
-				_, err := fmt.Fprintln(execution.Stdout, "stdout-output")
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), nil, os.ModePerm)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(workingDir, "libs", "shared"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "libs", "shared", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+			goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{
+				Replace: []gomodvendor.Replace{
+					{
+						Old:      gomodvendor.ModuleVersion{Path: "example.com/shared"},
+						New:      gomodvendor.ModuleVersion{Path: "./libs/shared"},
+						Position: gomodvendor.Position{File: filepath.Join(workingDir, "go.mod"), Line: 5},
+					},
+				},
+			}
+		})
+
+		it("returns the directory replacements of go.mod", func() {
+			replacements, err := modVendor.LocalReplacements(workingDir, workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(goModParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.mod")))
+			Expect(replacements).To(Equal([]gomodvendor.LocalReplacement{
+				{
+					Old:      gomodvendor.ModuleVersion{Path: "example.com/shared"},
+					New:      "./libs/shared",
+					Dir:      "libs/shared",
+					Position: gomodvendor.Position{File: filepath.Join(workingDir, "go.mod"), Line: 5},
+				},
+			}))
+		})
+
+		context("when the working directory is a workspace", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "libs", "other"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "libs", "other", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+				goModParser.ParseWorkCall.Returns.GoWork = gomodvendor.GoWork{
+					Use: []gomodvendor.Use{{Path: "."}, {Path: "./missing"}},
+					Replace: []gomodvendor.Replace{
+						{
+							Old: gomodvendor.ModuleVersion{Path: "example.com/other"},
+							New: gomodvendor.ModuleVersion{Path: "./libs/other"},
+						},
+					},
+				}
+			})
+
+			it("returns the directory replacements of go.work and of the modules it uses", func() {
+				replacements, err := modVendor.LocalReplacements(workingDir, workingDir, gomodvendor.BuildConfiguration{})
 				Expect(err).NotTo(HaveOccurred())
-				_, err = fmt.Fprintln(execution.Stderr, "stderr-output")
+
+				Expect(goModParser.ParseWorkCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.work")))
+				Expect(goModParser.ParseCall.CallCount).To(Equal(1))
+
+				Expect(replacements).To(HaveLen(2))
+				Expect(replacements[0].Dir).To(Equal("libs/other"))
+				Expect(replacements[1].Dir).To(Equal("libs/shared"))
+			})
+		})
+
+		context("when there is no go.mod file", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "go.mod"))).To(Succeed())
+			})
+
+			it("returns nothing", func() {
+				replacements, err := modVendor.LocalReplacements(workingDir, workingDir, gomodvendor.BuildConfiguration{})
 				Expect(err).NotTo(HaveOccurred())
-				return nil
+				Expect(replacements).To(BeEmpty())
+				Expect(goModParser.ParseCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a replacement directory does not exist", func() {
+				it.Before(func() {
+					Expect(os.RemoveAll(filepath.Join(workingDir, "libs"))).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.LocalReplacements(workingDir, workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError(fmt.Sprintf("%s:5: replace example.com/shared => ./libs/shared: '%s' does not exist", filepath.Join(workingDir, "go.mod"), filepath.Join(workingDir, "libs", "shared"))))
+				})
+			})
+
+			context("when go.mod cannot be parsed", func() {
+				it.Before(func() {
+					goModParser.ParseCall.Returns.Error = errors.New("failed to parse go.mod")
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.LocalReplacements(workingDir, workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to parse go.mod"))
+				})
+			})
+
+			context("when go.work cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+					goModParser.ParseWorkCall.Returns.Error = errors.New("failed to parse go.work")
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.LocalReplacements(workingDir, workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to parse go.work"))
+				})
+			})
+		})
+	})
+
+	context("ToolModules", func() {
+		var vendorDir string
+
+		it.Before(func() {
+			vendorDir = filepath.Join(workingDir, "vendor")
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), nil, os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "main.go"), []byte(`package main
+
+import _ "github.com/some/dependency"
+`), os.ModePerm)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(vendorDir, "github.com", "some", "dependency"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"), []byte("package dependency\n"), os.ModePerm)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(vendorDir, "github.com", "some", "tool", "lib"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "tool", "lib", "lib.go"), []byte("package lib\n"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(vendorDir, "modules.txt"), nil, os.ModePerm)).To(Succeed())
+
+			goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{
+				Path:   filepath.Join(workingDir, "go.mod"),
+				Module: gomodvendor.Module{Path: "example.com/server"},
+				Require: []gomodvendor.Require{
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/tool", Version: "v1.0.0"}},
+				},
+				Tool: []gomodvendor.Tool{{Path: "github.com/some/tool/cmd/tool"}},
+			}
+
+			goModParser.ParseModulesTxtCall.Returns.ModulesTxt = gomodvendor.ModulesTxt{
+				Modules: []gomodvendor.VendoredModule{
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"},
+						Packages:      []string{"github.com/some/dependency"},
+					},
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/tool", Version: "v1.0.0"},
+						Packages:      []string{"github.com/some/tool/cmd/tool", "github.com/some/tool/lib"},
+					},
+				},
 			}
 		})
-		it("runs go mod vendor", func() {
-			err := modVendor.Execute("mod-cache-path", workingDir)
+
+		it("returns the modules that provide the tools", func() {
+			modules, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"mod", "vendor"}))
-			Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, fmt.Sprintf("GOMODCACHE=%s", "mod-cache-path"))))
-			Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+			Expect(modules).To(Equal([]string{"github.com/some/tool"}))
 
-			Expect(logs.String()).To(ContainSubstring("  Executing build process"))
-			Expect(logs.String()).To(ContainSubstring("    Running 'go mod vendor'"))
-			Expect(logs.String()).To(ContainSubstring("      stdout-output"))
-			Expect(logs.String()).To(ContainSubstring("      stderr-output"))
-			Expect(logs.String()).To(ContainSubstring("      Completed in 1s"))
+			Expect(goModParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.mod")))
+			Expect(goModParser.ParseModulesTxtCall.Receives.Path).To(Equal(filepath.Join(vendorDir, "modules.txt")))
+			Expect(executable.ExecuteCall.CallCount).To(Equal(0))
+		})
+
+		context("when the packages of the module also import a package of the tool module", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "main.go"), []byte(`package main
+
+import _ "github.com/some/tool/lib"
+`), os.ModePerm)).To(Succeed())
+			})
+
+			it("does not return it", func() {
+				modules, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(BeEmpty())
+			})
+		})
+
+		context("when a dependency imports a package of the tool module", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"), []byte(`package dependency
+
+import _ "github.com/some/tool/lib"
+`), os.ModePerm)).To(Succeed())
+			})
+
+			it("does not return it", func() {
+				modules, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(BeEmpty())
+			})
+		})
+
+		context("when a package of the tool module is only imported for another platform", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "main_windows.go"), []byte(`//go:build windows
+
+package main
+
+import _ "github.com/some/tool/lib"
+`), os.ModePerm)).To(Succeed())
+			})
+
+			it("does not return it", func() {
+				modules, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(BeEmpty())
+			})
+		})
+
+		context("when a package of the tool module is only imported by tests or ignored files", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "main_test.go"), []byte(`package main
+
+import _ "github.com/some/tool/lib"
+`), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "generate.go"), []byte(`//go:build ignore
+
+package main
+
+import _ "github.com/some/tool/lib"
+`), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns it", func() {
+				modules, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(Equal([]string{"github.com/some/tool"}))
+			})
+		})
+
+		context("when the module declares no tools", func() {
+			it.Before(func() {
+				goModParser.ParseCall.Returns.GoMod.Tool = nil
+			})
+
+			it("does not read the dependencies", func() {
+				modules, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(BeEmpty())
+
+				Expect(goModParser.ParseModulesTxtCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when the modules are downloaded", func() {
+			var cacheDir string
+
+			it.Before(func() {
+				Expect(os.RemoveAll(vendorDir)).To(Succeed())
+
+				cacheDir = filepath.Join(workingDir, "some-mod-cache")
+				Expect(os.MkdirAll(filepath.Join(cacheDir, "github.com", "some", "dependency@v1.2.3"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(cacheDir, "github.com", "some", "dependency@v1.2.3", "dependency.go"), []byte(`//go:build darwin
+
+package dependency
+
+import _ "github.com/some/tool/lib"
+`), os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(cacheDir, "github.com", "some", "tool@v1.0.0", "lib"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(cacheDir, "github.com", "some", "tool@v1.0.0", "lib", "lib.go"), []byte("package lib\n"), os.ModePerm)).To(Succeed())
+
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					_, err := fmt.Fprintf(execution.Stdout, "example.com/server\t%s\ngithub.com/some/dependency\t%s\ngithub.com/some/tool\t%s\ngithub.com/not/downloaded\t\n",
+						workingDir,
+						filepath.Join(cacheDir, "github.com", "some", "dependency@v1.2.3"),
+						filepath.Join(cacheDir, "github.com", "some", "tool@v1.0.0"),
+					)
+					return err
+				}
+			})
+
+			it("reads the packages from the module cache", func() {
+				modules, err := modVendor.ToolModules(cacheDir, "", workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(BeEmpty())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"list", "-mod=mod", "-m", "-f", "{{.Path}}\t{{.Dir}}", "all"}))
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, "GOMODCACHE="+cacheDir)))
+				Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+			})
+		})
+
+		context("when the working directory is a workspace", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "tools", "cmd", "generate"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), nil, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "cmd", "generate", "main.go"), []byte(`package main
+
+import _ "github.com/other/linter"
+`), os.ModePerm)).To(Succeed())
+
+				goModParser.ParseWorkCall.Returns.GoWork = gomodvendor.GoWork{
+					Use: []gomodvendor.Use{{Path: "."}, {Path: "./tools"}},
+				}
+
+				goModParser.ParseCall.Stub = func(path string) (gomodvendor.GoMod, error) {
+					if path == filepath.Join(workingDir, "tools", "go.mod") {
+						return gomodvendor.GoMod{
+							Path:   path,
+							Module: gomodvendor.Module{Path: "example.com/tools"},
+							Require: []gomodvendor.Require{
+								{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/other/linter", Version: "v0.1.0"}},
+								{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/other/formatter", Version: "v0.2.0"}},
+							},
+							Tool: []gomodvendor.Tool{{Path: "github.com/other/linter"}, {Path: "github.com/other/formatter"}},
+						}, nil
+					}
+
+					return gomodvendor.GoMod{
+						Path:   path,
+						Module: gomodvendor.Module{Path: "example.com/server"},
+						Require: []gomodvendor.Require{
+							{ModuleVersion: gomodvendor.ModuleVersion{Path: "example.com/tools", Version: "v0.0.0"}},
+						},
+						Tool: []gomodvendor.Tool{{Path: "example.com/tools/cmd/generate"}},
+					}, nil
+				}
+
+				goModParser.ParseModulesTxtCall.Returns.ModulesTxt = gomodvendor.ModulesTxt{
+					Modules: []gomodvendor.VendoredModule{
+						{
+							ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/other/linter", Version: "v0.1.0"},
+							Packages:      []string{"github.com/other/linter"},
+						},
+						{
+							ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/other/formatter", Version: "v0.2.0"},
+							Packages:      []string{"github.com/other/formatter"},
+						},
+					},
+				}
+
+				Expect(os.MkdirAll(filepath.Join(vendorDir, "github.com", "other", "linter"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "other", "linter", "linter.go"), []byte("package main\n"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns the modules that provide the tools of every module, except workspace modules and modules their packages import", func() {
+				modules, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(modules).To(Equal([]string{"github.com/other/formatter"}))
+			})
 		})
 
 		context("failure cases", func() {
-			context("the executable fails", func() {
+			context("when go.mod cannot be parsed", func() {
 				it.Before(func() {
-					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
-						_, err := fmt.Fprintln(execution.Stdout, "build error stdout")
-						Expect(err).NotTo(HaveOccurred())
-						_, err = fmt.Fprintln(execution.Stderr, "build error stderr")
-						Expect(err).NotTo(HaveOccurred())
+					goModParser.ParseCall.Returns.Error = errors.New("failed to parse go.mod")
+				})
 
-						return errors.New("executable failed")
+				it("returns an error", func() {
+					_, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to parse go.mod"))
+				})
+			})
+
+			context("when modules.txt cannot be parsed", func() {
+				it.Before(func() {
+					goModParser.ParseModulesTxtCall.Returns.Error = errors.New("failed to parse modules.txt")
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to parse modules.txt"))
+				})
+			})
+
+			context("when a vendored package cannot be read", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"), []byte("not go"), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.ToolModules("some-mod-cache", vendorDir, workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError(ContainSubstring("failed to read the imports of %s", filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"))))
+				})
+			})
+
+			context("when the modules cannot be listed", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, _ = fmt.Fprintln(execution.Stderr, "go: some error")
+						return errors.New("exit status 1")
 					}
 				})
 
 				it("returns an error", func() {
-					err := modVendor.Execute("mod-cache-path", workingDir)
-					Expect(err).To(MatchError(ContainSubstring("executable failed")))
+					_, err := modVendor.ToolModules("some-mod-cache", "", workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to list the modules: exit status 1: go: some error"))
+				})
+			})
+		})
+	})
 
-					Expect(logs.String()).To(ContainSubstring("      build error stdout"))
-					Expect(logs.String()).To(ContainSubstring("      build error stderr"))
-					Expect(logs.String()).To(ContainSubstring("      Failed after 1s"))
+	context("VendorDigest", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module some-module\n"), os.ModePerm)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(workingDir, "vendor", "github.com", "some", "dependency"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "modules.txt"), nil, os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "github.com", "some", "dependency", "dependency.go"), []byte("package dependency\n"), os.ModePerm)).To(Succeed())
+
+			goModParser.ParseModulesTxtCall.Returns.ModulesTxt = gomodvendor.ModulesTxt{
+				Modules: []gomodvendor.VendoredModule{
+					{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"},
+						Packages:      []string{"github.com/some/dependency"},
+					},
+				},
+			}
+		})
+
+		it("returns the digest of the vendor directory and of go.mod", func() {
+			digest, ok, err := modVendor.VendorDigest(workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			Expect(goModParser.ParseModulesTxtCall.Receives.Path).To(Equal(filepath.Join(workingDir, "vendor", "modules.txt")))
+
+			Expect(digest.Digest).To(HavePrefix("h1:"))
+			Expect(digest.Modules).To(HaveKeyWithValue("github.com/some/dependency", HavePrefix("h1:")))
+
+			inputs, err := gomodvendor.ComputeInputsDigest(workingDir, "go.mod", "vendor/modules.txt")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digest.Inputs).To(Equal(inputs))
+		})
+
+		context("when vendor/modules.txt lists other packages", func() {
+			it("changes the digest of the inputs", func() {
+				previous, _, err := modVendor.VendorDigest(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "modules.txt"), []byte("# github.com/some/dependency v1.2.3\ngithub.com/some/dependency/other\n"), os.ModePerm)).To(Succeed())
+
+				current, _, err := modVendor.VendorDigest(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(current.Inputs).NotTo(Equal(previous.Inputs))
+			})
+		})
+
+		context("when the working directory is a workspace", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), []byte("go 1.22\n"), os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "api"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "api", "go.mod"), []byte("module api\n"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "api", "go.sum"), []byte("github.com/some/dependency v1.2.3 h1:abc=\n"), os.ModePerm)).To(Succeed())
+
+				goModParser.ParseWorkCall.Returns.GoWork = gomodvendor.GoWork{
+					Use: []gomodvendor.Use{{Path: "."}, {Path: "./api"}},
+				}
+			})
+
+			it("includes go.work and the module files of every module in the inputs", func() {
+				digest, ok, err := modVendor.VendorDigest(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				inputs, err := gomodvendor.ComputeInputsDigest(workingDir, "go.work", "go.mod", "api/go.mod", "api/go.sum", "vendor/modules.txt")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(digest.Inputs).To(Equal(inputs))
+
+				Expect(goModParser.ParseWorkCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.work")))
+			})
+		})
+
+		context("when there is no vendor/modules.txt", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "vendor", "modules.txt"))).To(Succeed())
+			})
+
+			it("is not ok", func() {
+				_, ok, err := modVendor.VendorDigest(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+				Expect(goModParser.ParseModulesTxtCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when modules.txt cannot be parsed", func() {
+			it.Before(func() {
+				goModParser.ParseModulesTxtCall.Returns.Error = errors.New("failed to parse modules.txt")
+			})
+
+			it("returns an error", func() {
+				_, _, err := modVendor.VendorDigest(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).To(MatchError("failed to parse modules.txt"))
+			})
+		})
+	})
+
+	context("ModCacheSettings", func() {
+		it.Before(func() {
+			executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				_, err := fmt.Fprintln(execution.Stdout, `{
+	"GOFLAGS": "-mod=mod",
+	"GOPRIVATE": "example.com/private",
+	"GOPROXY": "https://proxy.golang.org,direct",
+	"GOVERSION": "go1.23.4"
+}`)
+				return err
+			}
+		})
+
+		it("reads the settings from the go environment", func() {
+			settings, err := modVendor.ModCacheSettings(workingDir, gomodvendor.BuildConfiguration{DisableWorkspace: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings).To(Equal(gomodvendor.ModCacheSettings{
+				GoVersion: "go1.23.4",
+				GoProxy:   "https://proxy.golang.org,direct",
+				GoPrivate: "example.com/private",
+				GoFlags:   "-mod=mod",
+			}))
+
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"env", "-json", "GOVERSION", "GOPROXY", "GOPRIVATE", "GOFLAGS"}))
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, "GOWORK=off")))
+			Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+		})
+
+		context("when modules are downloaded from bindings", func() {
+			it("reads the settings with the proxies that Execute uses", func() {
+				_, err := modVendor.ModCacheSettings(workingDir, gomodvendor.BuildConfiguration{ModuleProxies: []string{"/bindings/some-binding"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment,
+					"GOPROXY=file:///bindings/some-binding,off",
+					"GONOPROXY=none",
+					"GOSUMDB=off",
+				)))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the go command fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, _ = fmt.Fprintln(execution.Stderr, "go: some error")
+						return errors.New("exit status 1")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.ModCacheSettings(workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to read the go environment: exit status 1: go: some error"))
+				})
+			})
+
+			context("when the output is not JSON", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stdout, "not json")
+						return err
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.ModCacheSettings(workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError(`failed to read the go environment: unexpected output "not json"`))
+				})
+			})
+		})
+	})
+
+	context("VendorKey", func() {
+		var version string
+
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module some-module\n"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.sum"), []byte("github.com/some/dependency v1.2.3 h1:some-hash=\n"), os.ModePerm)).To(Succeed())
+
+			version = "go1.23.4"
+			executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				_, err := fmt.Fprintln(execution.Stdout, version)
+				return err
+			}
+		})
+
+		it("changes with go.sum and the version of Go", func() {
+			key, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(MatchRegexp(`^[0-9a-f]{64}$`))
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"env", "GOVERSION"}))
+
+			same, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(same).To(Equal(key))
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.sum"), []byte("github.com/some/dependency v1.2.4 h1:other-hash=\n"), os.ModePerm)).To(Succeed())
+
+			changedSum, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changedSum).NotTo(Equal(key))
+
+			version = "go1.23.5"
+
+			changedVersion, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changedVersion).NotTo(Equal(changedSum))
+
+			excluded, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{ExcludeTools: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(excluded).NotTo(Equal(changedVersion))
+		})
+
+		context("when the module imports another package of a required module", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "main.go"), []byte("package main\n\nimport \"github.com/some/dependency/http2\"\n"), os.ModePerm)).To(Succeed())
+			})
+
+			it("changes although go.mod and go.sum are the same", func() {
+				key, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "main.go"), []byte("package main\n\n// Some comment.\nimport (\n\t\"github.com/some/dependency/http2\"\n)\n"), os.ModePerm)).To(Succeed())
+
+				same, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(same).To(Equal(key))
+
+				Expect(os.MkdirAll(filepath.Join(workingDir, "web"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "web", "web.go"), []byte("package web\n\nimport \"github.com/some/dependency/html\"\n"), os.ModePerm)).To(Succeed())
+
+				changed, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(changed).NotTo(Equal(key))
+			})
+
+			it("reads the imports of tests and of every platform, but not of ignored files", func() {
+				key, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "gen.go"), []byte("//go:build ignore\n\npackage main\n\nimport \"github.com/some/generator\"\n"), os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "testdata"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "testdata", "fixture.go"), []byte("package fixture\n\nimport \"github.com/some/fixture\"\n"), os.ModePerm)).To(Succeed())
+
+				ignored, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ignored).To(Equal(key))
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "main_windows.go"), []byte("//go:build windows && !cgo\n\npackage main\n\nimport \"golang.org/x/sys/windows\"\n"), os.ModePerm)).To(Succeed())
+
+				windows, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(windows).NotTo(Equal(key))
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "main_test.go"), []byte("package main\n\nimport \"github.com/some/assertions\"\n"), os.ModePerm)).To(Succeed())
+
+				test, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(test).NotTo(Equal(windows))
+			})
+
+			context("when a Go file cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "broken.go"), []byte("not go"), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError(ContainSubstring("failed to read the imports of %s", filepath.Join(workingDir, "broken.go"))))
+				})
+			})
+		})
+
+		context("when the working directory is a workspace", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), []byte("go 1.22\n\nuse ./tools\n"), os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), []byte("module some-tools\n"), os.ModePerm)).To(Succeed())
+
+				goModParser.ParseWorkCall.Returns.GoWork = gomodvendor.GoWork{
+					Use: []gomodvendor.Use{{Path: "./tools"}},
+				}
+			})
+
+			it("changes with the go.mod files of the workspace", func() {
+				key, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(goModParser.ParseWorkCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.work")))
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), []byte("module some-tools\n\ngo 1.22\n"), os.ModePerm)).To(Succeed())
+
+				changed, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(changed).NotTo(Equal(key))
+			})
+		})
+
+		context("when there is no go.mod file", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "go.mod"))).To(Succeed())
+			})
+
+			it("returns an empty key", func() {
+				key, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(key).To(BeEmpty())
+				Expect(executable.ExecuteCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when the version of Go cannot be read", func() {
+			it.Before(func() {
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					return errors.New("go env failed")
+				}
+			})
+
+			it("returns an error", func() {
+				_, err := modVendor.VendorKey(workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).To(MatchError(ContainSubstring("go env failed")))
+			})
+		})
+	})
+
+	context("VerifyCache", func() {
+		var modCachePath string
+
+		it.Before(func() {
+			modCachePath = filepath.Join(workingDir, "mod-cache")
+			moduleDir := filepath.Join(modCachePath, "github.com", "some", "dependency@v1.2.3")
+			Expect(os.MkdirAll(moduleDir, os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(moduleDir, "main.go"), nil, 0444)).To(Succeed())
+			Expect(os.Chmod(moduleDir, 0555)).To(Succeed())
+		})
+
+		it.After(func() {
+			if _, err := os.Stat(filepath.Join(modCachePath, "github.com", "some", "dependency@v1.2.3")); err == nil {
+				Expect(os.Chmod(filepath.Join(modCachePath, "github.com", "some", "dependency@v1.2.3"), os.ModePerm)).To(Succeed())
+			}
+		})
+
+		it("runs go mod verify", func() {
+			err := modVendor.VerifyCache(modCachePath, workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"mod", "verify"}))
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, fmt.Sprintf("GOMODCACHE=%s", modCachePath))))
+			Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+
+			Expect(modCachePath).To(BeADirectory())
+
+			Expect(logs.String()).To(ContainSubstring("  Verifying the module cache"))
+			Expect(logs.String()).To(ContainSubstring("    Running 'go mod verify'"))
+			Expect(logs.String()).To(ContainSubstring("      Completed in 1s"))
+		})
+
+		context("when the module cache is empty", func() {
+			it.Before(func() {
+				Expect(os.Chmod(filepath.Join(modCachePath, "github.com", "some", "dependency@v1.2.3"), os.ModePerm)).To(Succeed())
+				Expect(os.RemoveAll(modCachePath)).To(Succeed())
+			})
+
+			it("does not run go mod verify", func() {
+				err := modVendor.VerifyCache(modCachePath, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(executable.ExecuteCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when modules fail verification", func() {
+			it.Before(func() {
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					_, err := fmt.Fprintf(execution.Stdout, "github.com/some/dependency v1.2.3: dir has been modified (%s)\n", execution.Dir)
+					Expect(err).NotTo(HaveOccurred())
+					_, err = fmt.Fprintln(execution.Stdout, "github.com/some-other/dependency v0.0.4: missing ziphash: open hash: no such file or directory")
+					Expect(err).NotTo(HaveOccurred())
+					return errors.New("exit status 1")
+				}
+			})
+
+			it("logs them and removes the module cache", func() {
+				err := modVendor.VerifyCache(modCachePath, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(modCachePath).NotTo(BeAnExistingFile())
+
+				Expect(logs.String()).To(ContainSubstring("      Failed after 1s"))
+				Expect(logs.String()).To(ContainSubstring("    The following modules failed verification"))
+				Expect(logs.String()).To(ContainSubstring(fmt.Sprintf("      github.com/some/dependency v1.2.3: dir has been modified (%s)", workingDir)))
+				Expect(logs.String()).To(ContainSubstring("      github.com/some-other/dependency v0.0.4: missing ziphash"))
+				Expect(logs.String()).To(ContainSubstring("    Removing the module cache to download every module again"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when go mod verify fails for another reason", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stderr, "go: go.mod file not found")
+						Expect(err).NotTo(HaveOccurred())
+						return errors.New("exit status 1")
+					}
+				})
+
+				it("returns the error and keeps the module cache", func() {
+					err := modVendor.VerifyCache(modCachePath, workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("exit status 1"))
+
+					Expect(modCachePath).To(BeADirectory())
+					Expect(logs.String()).To(ContainSubstring("      go: go.mod file not found"))
+				})
+			})
+		})
+	})
+
+	context("PruneCache", func() {
+		var modCacheDir string
+
+		it.Before(func() {
+			var err error
+			modCacheDir, err = os.MkdirTemp("", "mod-cache")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(modCacheDir, "github.com", "old", "module@v0.1.0"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(modCacheDir, "github.com", "old", "module@v0.1.0", "module.go"), []byte("package module\n"), 0444)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(modCacheDir, "github.com", "some", "dependency@v1.2.3"), os.ModePerm)).To(Succeed())
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.sum"), nil, os.ModePerm)).To(Succeed())
+
+			goModParser.ParseGoSumCall.Returns.GoSum = gomodvendor.GoSum{
+				Entries: []gomodvendor.SumEntry{
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}, Hash: "h1:some-hash="},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3/go.mod"}, Hash: "h1:some-hash="},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.0.0/go.mod"}, Hash: "h1:some-hash="},
+				},
+			}
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(modCacheDir)).To(Succeed())
+		})
+
+		it("removes the modules that go.sum does not refer to and reports the reclaimed bytes", func() {
+			resolved, err := modVendor.PruneCache(modCacheDir, []string{workingDir}, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resolved).To(Equal([]string{"github.com/some/dependency@v1.2.3"}))
+
+			Expect(goModParser.ParseGoSumCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.sum")))
+
+			Expect(filepath.Join(modCacheDir, "github.com", "old")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(modCacheDir, "github.com", "some", "dependency@v1.2.3")).To(BeADirectory())
+
+			Expect(logs.String()).To(ContainSubstring("  Pruning the module cache"))
+			Expect(logs.String()).To(ContainSubstring("    Reclaimed 15 B"))
+		})
+
+		context("when the working directory is a workspace", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work.sum"), nil, os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.sum"), nil, os.ModePerm)).To(Succeed())
+
+				goModParser.ParseWorkCall.Returns.GoWork = gomodvendor.GoWork{
+					Use: []gomodvendor.Use{{Path: "."}, {Path: "./tools"}},
+				}
+			})
+
+			it("reads go.work.sum and the go.sum file of every module", func() {
+				_, err := modVendor.PruneCache(modCacheDir, []string{workingDir}, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(goModParser.ParseGoSumCall.CallCount).To(Equal(3))
+				Expect(goModParser.ParseGoSumCall.Receives.Path).To(Equal(filepath.Join(workingDir, "tools", "go.sum")))
+			})
+		})
+
+		context("when the module cache does not exist", func() {
+			it("does nothing", func() {
+				_, err := modVendor.PruneCache(filepath.Join(modCacheDir, "missing"), []string{workingDir}, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(goModParser.ParseGoSumCall.CallCount).To(Equal(0))
+				Expect(logs.String()).To(BeEmpty())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when go.sum cannot be parsed", func() {
+				it.Before(func() {
+					goModParser.ParseGoSumCall.Returns.Error = errors.New("failed to parse go.sum")
+				})
+
+				it("returns an error", func() {
+					_, err := modVendor.PruneCache(modCacheDir, []string{workingDir}, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("failed to parse go.sum"))
+				})
+			})
+		})
+	})
+
+	context("CheckTidy", func() {
+		var executions []pexec.Execution
+
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+			executions = nil
+			executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				executions = append(executions, execution)
+				if execution.Args[0] == "env" {
+					_, err := fmt.Fprintln(execution.Stdout, "go1.23.4")
+					return err
+				}
+
+				return nil
+			}
+		})
+
+		it("runs go mod tidy -diff", func() {
+			err := modVendor.CheckTidy("mod-cache-path", workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executions).To(HaveLen(2))
+			Expect(executions[0].Args).To(Equal([]string{"env", "GOVERSION"}))
+			Expect(executions[1].Args).To(Equal([]string{"mod", "tidy", "-diff"}))
+			Expect(executions[1].Env).To(Equal(append(environment, "GOMODCACHE=mod-cache-path", "GOWORK=off")))
+			Expect(executions[1].Dir).To(Equal(workingDir))
+
+			Expect(logs.String()).To(ContainSubstring("  Checking that go.mod and go.sum are tidy"))
+			Expect(logs.String()).To(ContainSubstring("    Running 'go mod tidy -diff'"))
+			Expect(logs.String()).To(ContainSubstring("      Completed in 1s"))
+		})
+
+		context("when go.mod and go.sum are not tidy", func() {
+			it.Before(func() {
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					if execution.Args[0] == "env" {
+						_, err := fmt.Fprintln(execution.Stdout, "go1.23.4")
+						return err
+					}
+
+					_, err := fmt.Fprint(execution.Stdout, "--- current/go.mod\n+++ tidy/go.mod\n@@ -3 +3 @@\n-require github.com/unused/dependency v1.0.0\n")
+					Expect(err).NotTo(HaveOccurred())
+					return errors.New("exit status 1")
+				}
+			})
+
+			it("returns an error and shows the diff", func() {
+				err := modVendor.CheckTidy("mod-cache-path", workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).To(MatchError("go.mod and go.sum are not tidy: run 'go mod tidy' and commit the result"))
+
+				Expect(logs.String()).To(ContainSubstring("      go.mod and go.sum are not tidy, go mod tidy would make these changes:"))
+				Expect(logs.String()).To(ContainSubstring("        --- current/go.mod\n        +++ tidy/go.mod\n        @@ -3 +3 @@\n        -require github.com/unused/dependency v1.0.0\n"))
+			})
+		})
+
+		context("when the working directory is a workspace", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(workingDir, "api"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "api", "go.mod"), nil, os.ModePerm)).To(Succeed())
+
+				goModParser.ParseWorkCall.Returns.GoWork = gomodvendor.GoWork{
+					Use: []gomodvendor.Use{{Path: "."}, {Path: "./api"}},
+				}
+			})
+
+			it("checks every module of the workspace", func() {
+				err := modVendor.CheckTidy("mod-cache-path", workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(HaveLen(3))
+				Expect(executions[1].Dir).To(Equal(workingDir))
+				Expect(executions[2].Dir).To(Equal(filepath.Join(workingDir, "api")))
+
+				Expect(logs.String()).To(ContainSubstring("    Running 'go mod tidy -diff' in 'api'"))
+			})
+		})
+
+		context("when go.mod is missing", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "go.mod"))).To(Succeed())
+			})
+
+			it("skips the check", func() {
+				err := modVendor.CheckTidy("mod-cache-path", workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(executions).To(HaveLen(1))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the installed Go is older than 1.23", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stdout, "go1.22.5")
+						return err
+					}
+				})
+
+				it("returns an error", func() {
+					err := modVendor.CheckTidy("mod-cache-path", workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("BP_GO_MOD_TIDY_CHECK needs 'go mod tidy -diff', which requires Go 1.23 or later, but Go 1.22.5 is installed: request a newer Go with BP_GO_VERSION or unset BP_GO_MOD_TIDY_CHECK"))
+				})
+			})
+
+			context("when the Go version cannot be determined", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stdout, "devel")
+						return err
+					}
+				})
+
+				it("returns an error", func() {
+					err := modVendor.CheckTidy("mod-cache-path", workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError(`failed to determine the installed go version: unexpected output "devel"`))
+				})
+			})
+
+			context("when go mod tidy fails without a diff", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						if execution.Args[0] == "env" {
+							_, err := fmt.Fprintln(execution.Stdout, "go1.23.4")
+							return err
+						}
+
+						return errors.New("network unreachable")
+					}
+				})
+
+				it("returns the error", func() {
+					err := modVendor.CheckTidy("mod-cache-path", workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("network unreachable"))
+					Expect(logs.String()).To(ContainSubstring("      Failed after 1s"))
+				})
+			})
+		})
+	})
+
+	context("Execute", func() {
+		it.Before(func() {
+			executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				_, err := fmt.Fprintln(execution.Stdout, "stdout-output")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = fmt.Fprintln(execution.Stderr, "stderr-output")
+				Expect(err).NotTo(HaveOccurred())
+				return nil
+			}
+		})
+		it("runs go mod vendor", func() {
+			err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"mod", "vendor"}))
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, fmt.Sprintf("GOMODCACHE=%s", "mod-cache-path"))))
+			Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+
+			Expect(logs.String()).To(ContainSubstring("  Executing build process"))
+			Expect(logs.String()).To(ContainSubstring("    Running 'go mod vendor'"))
+			Expect(logs.String()).To(ContainSubstring("      stdout-output"))
+			Expect(logs.String()).To(ContainSubstring("      stderr-output"))
+			Expect(logs.String()).To(ContainSubstring("      Completed in 1s"))
+		})
+
+		context("when the vendor mode is download", func() {
+			it("runs go mod download", func() {
+				err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{VendorMode: "download"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"mod", "download"}))
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, "GOMODCACHE=mod-cache-path")))
+				Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+
+				Expect(logs.String()).To(ContainSubstring("    Running 'go mod download'"))
+			})
+		})
+
+		context("when module proxies are configured", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.sum"), nil, 0644)).To(Succeed())
+				goModParser.ParseGoSumCall.Returns.GoSum = gomodvendor.GoSum{
+					Entries: []gomodvendor.SumEntry{
+						{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}},
+						{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3/go.mod"}},
+					},
+				}
+			})
+
+			it("downloads modules from them and never from the network", func() {
+				err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{
+					ModuleProxies: []string{"/bindings/some-binding", "/tmp/other-binding"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment,
+					"GOMODCACHE=mod-cache-path",
+					"GOPROXY=file:///bindings/some-binding,file:///tmp/other-binding,off",
+					"GONOPROXY=none",
+					"GOSUMDB=off",
+				)))
+
+				Expect(logs.String()).To(ContainSubstring("    Checking that go.sum has an entry for every required module"))
+			})
+
+			context("when go.sum is missing entries", func() {
+				it.Before(func() {
+					goModParser.ParseCall.Returns.GoMod.Require = []gomodvendor.Require{
+						{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/new/dependency", Version: "v0.1.0"}},
+					}
+				})
+
+				it("returns an error listing them without running the go command", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{
+						ModuleProxies: []string{"/bindings/some-binding"},
+					})
+					Expect(err).To(MatchError("go.sum is missing 2 entries required by the go-mod-cache binding:\n  github.com/new/dependency v0.1.0/go.mod\n  github.com/new/dependency v0.1.0\n\nrun 'go mod tidy' and commit go.sum"))
+					Expect(executable.ExecuteCall.CallCount).To(Equal(0))
+				})
+			})
+		})
+
+		context("when the working directory contains a go.work file", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.work"), nil, os.ModePerm)).To(Succeed())
+			})
+
+			it("runs go work vendor", func() {
+				err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"work", "vendor"}))
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, fmt.Sprintf("GOMODCACHE=%s", "mod-cache-path"))))
+				Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+
+				Expect(logs.String()).To(ContainSubstring("    Running 'go work vendor'"))
+			})
+
+			context("when workspaces are disabled", func() {
+				it("runs go mod vendor with GOWORK=off", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{DisableWorkspace: true})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"mod", "vendor"}))
+					Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, "GOMODCACHE=mod-cache-path", "GOWORK=off")))
+
+					Expect(logs.String()).To(ContainSubstring("    Running 'go mod vendor'"))
+				})
+			})
+		})
+
+		context("when the vendor path is outside of the module", func() {
+			var vendorPath string
+
+			it.Before(func() {
+				vendorPath = filepath.Join(workingDir, "layers", "vendor", "vendor")
+
+				Expect(os.MkdirAll(filepath.Join(workingDir, "vendor"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "modules.txt"), []byte("committed\n"), os.ModePerm)).To(Succeed())
+			})
+
+			it("runs go mod vendor with -o and leaves the vendor directory of the module alone", func() {
+				err := modVendor.Execute("mod-cache-path", vendorPath, workingDir, gomodvendor.BuildConfiguration{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"mod", "vendor", "-o", vendorPath}))
+				Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+				Expect(filepath.Join(workingDir, "layers", "vendor")).To(BeADirectory())
+
+				content, err := os.ReadFile(filepath.Join(workingDir, "vendor", "modules.txt"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("committed\n"))
+
+				Expect(logs.String()).To(ContainSubstring(fmt.Sprintf("    Running 'go mod vendor -o %s'", vendorPath)))
+			})
+
+			context("when the vendor mode is download", func() {
+				it("runs go mod download without -o", func() {
+					err := modVendor.Execute("mod-cache-path", vendorPath, workingDir, gomodvendor.BuildConfiguration{VendorMode: "download"})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"mod", "download"}))
+				})
+			})
+		})
+
+		context("when tools are excluded", func() {
+			var executions []pexec.Execution
+
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module example.com/server\n\ntool github.com/some/tool/cmd/tool\n"), 0644)).To(Succeed())
+
+				goModParser.ParseCall.Returns.GoMod = gomodvendor.GoMod{
+					Path: filepath.Join(workingDir, "go.mod"),
+					Tool: []gomodvendor.Tool{
+						{Path: "github.com/some/tool/cmd/tool"},
+						{Path: "github.com/other/linter"},
+					},
+				}
+
+				executions = nil
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					executions = append(executions, execution)
+
+					if execution.Args[0] == "mod" && execution.Args[1] == "edit" {
+						return os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module example.com/server\n"), 0644)
+					}
+
+					content, err := os.ReadFile(filepath.Join(workingDir, "go.mod"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).NotTo(ContainSubstring("tool"))
+
+					return nil
+				}
+			})
+
+			it("drops the tool directives while vendoring", func() {
+				err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{ExcludeTools: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(HaveLen(2))
+				Expect(executions[0].Args).To(Equal([]string{"mod", "edit", "-droptool=github.com/some/tool/cmd/tool", "-droptool=github.com/other/linter"}))
+				Expect(executions[0].Dir).To(Equal(workingDir))
+				Expect(executions[1].Args).To(Equal([]string{"mod", "vendor"}))
+
+				content, err := os.ReadFile(filepath.Join(workingDir, "go.mod"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("module example.com/server\n\ntool github.com/some/tool/cmd/tool\n"))
+
+				Expect(logs.String()).To(ContainSubstring("Excluding tools from the vendor directory"))
+				Expect(logs.String()).To(ContainSubstring("github.com/some/tool/cmd/tool"))
+			})
+
+			context("when the module declares no tools", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module example.com/server\n"), 0644)).To(Succeed())
+					goModParser.ParseCall.Returns.GoMod.Tool = nil
+				})
+
+				it("only runs go mod vendor", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{ExcludeTools: true})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(executions).To(HaveLen(1))
+					Expect(executions[0].Args).To(Equal([]string{"mod", "vendor"}))
+				})
+			})
+
+			context("when vendoring fails", func() {
+				it.Before(func() {
+					stub := executable.ExecuteCall.Stub
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						err := stub(execution)
+						if err != nil || execution.Args[1] == "edit" {
+							return err
+						}
+
+						return errors.New("failed to vendor")
+					}
+				})
+
+				it("restores go.mod and returns an error", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{ExcludeTools: true})
+					Expect(err).To(MatchError("failed to vendor"))
+
+					content, err := os.ReadFile(filepath.Join(workingDir, "go.mod"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(ContainSubstring("tool github.com/some/tool/cmd/tool"))
+				})
+			})
+		})
+
+		context("when strict mode is enabled", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module github.com/some-org/some-app\n"), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "go.sum"), []byte("original\n"), 0644)).To(Succeed())
+
+				goModParser.ParseGoSumCall.Returns.GoSum = gomodvendor.GoSum{
+					Entries: []gomodvendor.SumEntry{
+						{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}},
+						{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3/go.mod"}},
+					},
+				}
+			})
+
+			it("checks go.sum and runs go mod vendor", func() {
+				err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{Strict: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"mod", "vendor"}))
+				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(environment, "GOMODCACHE=mod-cache-path")))
+
+				Expect(goModParser.ParseCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.mod")))
+				Expect(goModParser.ParseGoSumCall.Receives.Path).To(Equal(filepath.Join(workingDir, "go.sum")))
+
+				Expect(logs.String()).To(ContainSubstring("    Checking that go.sum has an entry for every required module"))
+			})
+
+			context("when go.sum is missing entries", func() {
+				it.Before(func() {
+					goModParser.ParseCall.Returns.GoMod.Require = append(goModParser.ParseCall.Returns.GoMod.Require, gomodvendor.Require{
+						ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/new/dependency", Version: "v0.1.0"},
+					})
+				})
+
+				it("returns an error listing them without vendoring", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{Strict: true})
+					Expect(err).To(MatchError("go.sum is missing 2 entries required by BP_GO_MOD_STRICT:\n  github.com/new/dependency v0.1.0/go.mod\n  github.com/new/dependency v0.1.0\n\nrun 'go mod tidy' and commit go.sum"))
+					Expect(executable.ExecuteCall.CallCount).To(Equal(0))
+				})
+			})
+
+			context("when the go command changes go.sum", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						return os.WriteFile(filepath.Join(execution.Dir, "go.sum"), []byte("changed\n"), 0644)
+					}
+				})
+
+				it("restores it and returns an error", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{Strict: true})
+					Expect(err).To(MatchError("'go mod vendor' changed go.sum, which is not allowed with BP_GO_MOD_STRICT"))
+
+					content, err := os.ReadFile(filepath.Join(workingDir, "go.sum"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(Equal("original\n"))
+				})
+			})
+		})
+
+		context("when a vendor directory is already present", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "vendor", "github.com", "removed", "dependency"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "modules.txt"), []byte(`# github.com/some/dependency v1.2.0
+## explicit
+github.com/some/dependency
+# github.com/removed/dependency v0.1.0
+## explicit
+github.com/removed/dependency
+# github.com/unchanged/dependency v0.3.0
+## explicit
+github.com/unchanged/dependency
+`), os.ModePerm)).To(Succeed())
+
+				goModParser.ParseModulesTxtCall.Stub = gomodvendor.NewGoModParser().ParseModulesTxt
+			})
+
+			it("replaces it and logs the changed modules", func() {
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					vendorDir := filepath.Join(execution.Dir, "vendor")
+					Expect(vendorDir).NotTo(BeADirectory())
+
+					Expect(os.MkdirAll(vendorDir, os.ModePerm)).To(Succeed())
+					return os.WriteFile(filepath.Join(vendorDir, "modules.txt"), []byte(`# github.com/some/dependency v1.2.3
+## explicit
+github.com/some/dependency
+# github.com/added/dependency v2.0.0
+github.com/added/dependency
+# github.com/unchanged/dependency v0.3.0
+## explicit
+github.com/unchanged/dependency
+`), os.ModePerm)
+				}
+
+				err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{Revendor: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(workingDir, "vendor", "github.com", "removed")).NotTo(BeADirectory())
+
+				entries, err := os.ReadDir(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Name()).To(Equal("vendor"))
+
+				Expect(logs.String()).To(ContainSubstring("    Changes compared to the previous vendor directory"))
+				Expect(logs.String()).To(ContainSubstring("      Added github.com/added/dependency v2.0.0"))
+				Expect(logs.String()).To(ContainSubstring("      Removed github.com/removed/dependency v0.1.0"))
+				Expect(logs.String()).To(ContainSubstring("      Changed github.com/some/dependency v1.2.0 -> v1.2.3"))
+				Expect(logs.String()).NotTo(ContainSubstring("unchanged"))
+			})
+
+			context("when nothing changed", func() {
+				it("says so", func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						matches, err := filepath.Glob(filepath.Join(execution.Dir, ".vendor-*", "vendor"))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(matches).To(HaveLen(1))
+
+						content, err := os.ReadFile(filepath.Join(matches[0], "modules.txt"))
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(os.MkdirAll(filepath.Join(execution.Dir, "vendor"), os.ModePerm)).To(Succeed())
+						return os.WriteFile(filepath.Join(execution.Dir, "vendor", "modules.txt"), content, os.ModePerm)
+					}
+
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{Revendor: true})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(logs.String()).To(ContainSubstring("    No modules changed compared to the previous vendor directory"))
+				})
+			})
+
+			context("when vendoring fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						Expect(os.MkdirAll(filepath.Join(execution.Dir, "vendor", "github.com", "partial"), os.ModePerm)).To(Succeed())
+						return errors.New("executable failed")
+					}
+				})
+
+				it("restores the previous vendor directory", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{Revendor: true})
+					Expect(err).To(MatchError("executable failed"))
+
+					Expect(filepath.Join(workingDir, "vendor", "github.com", "removed", "dependency")).To(BeADirectory())
+					Expect(filepath.Join(workingDir, "vendor", "github.com", "partial")).NotTo(BeADirectory())
+
+					content, err := os.ReadFile(filepath.Join(workingDir, "vendor", "modules.txt"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(HavePrefix("# github.com/some/dependency v1.2.0\n"))
+
+					entries, err := os.ReadDir(workingDir)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(entries).To(HaveLen(1))
+
+					Expect(logs.String()).To(ContainSubstring("      Restored the previous vendor directory"))
+				})
+			})
+		})
+
+		context("when a legacy dependency manager is migrated", func() {
+			var executions []pexec.Execution
+
+			it.Before(func() {
+				executions = nil
+				executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					executions = append(executions, execution)
+					return nil
+				}
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gopkg.toml"), nil, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "Gopkg.lock"), []byte(`
+[[projects]]
+  name = "github.com/some/dependency"
+  revision = "0123456789abcdef0123456789abcdef01234567"
+  version = "v1.2.3"
+
+[[projects]]
+  name = "github.com/some-other/dependency"
+  revision = "89abcdef0123456789abcdef0123456789abcdef"
+`), os.ModePerm)).To(Succeed())
+			})
+
+			it("generates a go.mod file before vendoring", func() {
+				err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{
+					MigrateLegacy:     true,
+					MigrateModulePath: "github.com/some-org/some-app",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executions).To(HaveLen(3))
+				Expect(executions[0].Args).To(Equal([]string{"mod", "init", "github.com/some-org/some-app"}))
+				Expect(executions[1].Args).To(Equal([]string{
+					"get",
+					"github.com/some/dependency@v1.2.3",
+					"github.com/some-other/dependency@89abcdef0123456789abcdef0123456789abcdef",
+				}))
+				Expect(executions[2].Args).To(Equal([]string{"mod", "vendor"}))
+
+				for _, execution := range executions {
+					Expect(execution.Env).To(Equal(append(environment, "GOMODCACHE=mod-cache-path")))
+					Expect(execution.Dir).To(Equal(workingDir))
+				}
+
+				Expect(logs.String()).To(ContainSubstring("    Migrating from dep using Gopkg.lock"))
+				Expect(logs.String()).To(ContainSubstring("    Running 'go mod init github.com/some-org/some-app'"))
+			})
+
+			context("when a go.mod file is already present", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), nil, os.ModePerm)).To(Succeed())
+				})
+
+				it("only vendors", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{MigrateLegacy: true})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(executions).To(HaveLen(1))
+					Expect(executions[0].Args).To(Equal([]string{"mod", "vendor"}))
+				})
+			})
+
+			context("when the lock file is missing", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(workingDir, "Gopkg.lock"))).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{MigrateLegacy: true})
+					Expect(err).To(MatchError("failed to migrate from dep: Gopkg.lock is required to pin dependency versions"))
+					Expect(executions).To(BeEmpty())
+				})
+			})
+
+			context("when the module path is not known", func() {
+				it("returns an error before running go mod init", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{MigrateLegacy: true})
+					Expect(err).To(MatchError("failed to migrate from dep: the module path of the app is not recorded in Gopkg.toml, set BP_GO_MOD_MIGRATE_MODULE to the module path to pass to go mod init"))
+					Expect(executions).To(BeEmpty())
+				})
+			})
+		})
+
+		context("failure cases", func() {
+			context("the executable fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stdout, "build error stdout")
+						Expect(err).NotTo(HaveOccurred())
+						_, err = fmt.Fprintln(execution.Stderr, "build error stderr")
+						Expect(err).NotTo(HaveOccurred())
+
+						return errors.New("executable failed")
+					}
+				})
+
+				it("returns an error", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError(ContainSubstring("executable failed")))
+
+					Expect(logs.String()).To(ContainSubstring("      build error stdout"))
+					Expect(logs.String()).To(ContainSubstring("      build error stderr"))
+					Expect(logs.String()).To(ContainSubstring("      Failed after 1s"))
+				})
+			})
+
+			context("go.sum lacks the checksum of a module that provides packages", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stderr, "go: github.com/some-org/some-app imports")
+						Expect(err).NotTo(HaveOccurred())
+						_, err = fmt.Fprintln(execution.Stderr, "\tgithub.com/some/dependency/pkg: missing go.sum entry for module providing package github.com/some/dependency/pkg (imported by github.com/some-org/some-app); to add:")
+						Expect(err).NotTo(HaveOccurred())
+						_, err = fmt.Fprintln(execution.Stderr, "\tgo get github.com/some-org/some-app")
+						Expect(err).NotTo(HaveOccurred())
+
+						return errors.New("exit status 1")
+					}
+				})
+
+				it("returns an error that lists the packages", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{})
+					Expect(err).To(MatchError("go.sum is missing the checksums of the modules that provide these packages:\n  github.com/some/dependency/pkg\n\nrun 'go mod tidy' and commit go.sum: exit status 1"))
+				})
+			})
+
+			context("the module proxies do not provide every module", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "go.sum"), nil, 0644)).To(Succeed())
+					goModParser.ParseGoSumCall.Returns.GoSum = gomodvendor.GoSum{
+						Entries: []gomodvendor.SumEntry{
+							{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}},
+							{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3/go.mod"}},
+						},
+					}
+
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						_, err := fmt.Fprintln(execution.Stderr, "go: github.com/some/dependency@v1.2.3: module lookup disabled by GOPROXY=off")
+						Expect(err).NotTo(HaveOccurred())
+						_, err = fmt.Fprintln(execution.Stderr, "go: github.com/some-org/some-app imports")
+						Expect(err).NotTo(HaveOccurred())
+						_, err = fmt.Fprintln(execution.Stderr, "\tgithub.com/another/dependency/pkg: module lookup disabled by GOPROXY=off")
+						Expect(err).NotTo(HaveOccurred())
+						_, err = fmt.Fprintln(execution.Stderr, "go: github.com/some/dependency@v1.2.3: module lookup disabled by GOPROXY=off")
+						Expect(err).NotTo(HaveOccurred())
+
+						return errors.New("exit status 1")
+					}
+				})
+
+				it("returns an error that lists the missing modules", func() {
+					err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{
+						ModuleProxies: []string{"/bindings/some-binding"},
+					})
+					Expect(err).To(MatchError(strings.Join([]string{
+						"'go mod vendor' needs modules that GOPROXY does not provide:",
+						"  github.com/another/dependency/pkg",
+						"  github.com/some/dependency@v1.2.3",
+						"",
+						"add them to the go-mod-cache binding",
+					}, "\n")))
+					Expect(errors.Unwrap(errors.Unwrap(err))).To(MatchError("exit status 1"))
+
+					Expect(logs.String()).To(ContainSubstring("module lookup disabled by GOPROXY=off"))
+				})
+
+				context("when no module proxy is configured", func() {
+					it("does not mention the binding", func() {
+						err := modVendor.Execute("mod-cache-path", filepath.Join(workingDir, "vendor"), workingDir, gomodvendor.BuildConfiguration{})
+						Expect(err).To(MatchError(strings.Join([]string{
+							"'go mod vendor' needs modules that GOPROXY does not provide:",
+							"  github.com/another/dependency/pkg",
+							"  github.com/some/dependency@v1.2.3",
+						}, "\n")))
+					})
 				})
 			})
 		})
@@ -0,0 +1,162 @@
+package gomodvendor
+
+import (
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	"github.com/paketo-buildpacks/packit/v2/vacation"
+)
+
+// ModuleCacheBindingType is the type of the service bindings that provide
+// modules to builds that cannot reach a module proxy.
+const ModuleCacheBindingType = "go-mod-cache"
+
+//go:generate faux --interface BindingResolver --output fakes/binding_resolver.go
+type BindingResolver interface {
+	Resolve(typ, provider, platformDir string) ([]servicebindings.Binding, error)
+}
+
+// ModuleProxyDir returns the directory in GOPROXY layout that a go-mod-cache
+// binding provides. The binding holds either that directory, a copy of a
+// module cache whose cache/download directory is used, or a single tarball
+// of either, which is extracted into tmpDir.
+func ModuleProxyDir(binding servicebindings.Binding, tmpDir string) (string, error) {
+	var names []string
+	for name := range binding.Entries {
+		if name != "type" && name != "provider" {
+			names = append(names, name)
+		}
+	}
+
+	root := binding.Path
+	if len(names) == 1 {
+		info, err := os.Stat(filepath.Join(binding.Path, names[0]))
+		if err != nil {
+			return "", err
+		}
+
+		if info.Mode().IsRegular() {
+			root = filepath.Join(tmpDir, binding.Name)
+			err = extractArchive(filepath.Join(binding.Path, names[0]), root)
+			if err != nil {
+				return "", fmt.Errorf("failed to extract the '%s' binding: %w", binding.Name, err)
+			}
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(root, "cache", "download"))
+	if err == nil && info.IsDir() {
+		root = filepath.Join(root, "cache", "download")
+	}
+
+	found, err := hasModules(root)
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		return "", fmt.Errorf("the '%s' binding does not contain modules in GOPROXY layout, such as <module>/@v/<version>.zip", binding.Name)
+	}
+
+	return root, nil
+}
+
+func extractArchive(path, destination string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = os.MkdirAll(destination, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return vacation.NewArchive(file).Decompress(destination)
+}
+
+// hasModules tells whether the directory holds the @v directory of at least
+// one module.
+func hasModules(dir string) (bool, error) {
+	errFound := errors.New("found")
+	err := filepath.WalkDir(dir, func(path string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() && entry.Name() == "@v" {
+			return errFound
+		}
+
+		return nil
+	})
+	if errors.Is(err, errFound) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// proxyEnv points the go command at the module proxies, and never at the
+// network: modules matching GOPRIVATE or GONOPROXY are also downloaded from
+// the proxies rather than from their repository, and the checksum database
+// is not consulted, leaving go.sum as the only source of checksums.
+func proxyEnv(config BuildConfiguration) []string {
+	if len(config.ModuleProxies) == 0 {
+		return nil
+	}
+
+	var proxies []string
+	for _, dir := range config.ModuleProxies {
+		proxies = append(proxies, "file://"+filepath.ToSlash(dir))
+	}
+
+	return []string{
+		fmt.Sprintf("GOPROXY=%s,off", strings.Join(proxies, ",")),
+		"GONOPROXY=none",
+		"GOSUMDB=off",
+	}
+}
+
+var lookupDisabledPattern = regexp.MustCompile(`(\S+): module lookup disabled by GOPROXY=off`)
+
+// missingModules returns the modules that the go command could not download
+// because GOPROXY ends with off, from its output. go mod download names the
+// module version, such as example.com/x@v1.2.3, while go mod vendor names the
+// imported package.
+func missingModules(output string) []string {
+	var missing []string
+	for _, match := range lookupDisabledPattern.FindAllStringSubmatch(output, -1) {
+		if !slices.Contains(missing, match[1]) {
+			missing = append(missing, match[1])
+		}
+	}
+
+	slices.Sort(missing)
+
+	return missing
+}
+
+// missingModulesError is returned when the go command cannot download
+// modules because GOPROXY ends with off.
+type missingModulesError struct {
+	args    []string
+	modules []string
+	err     error
+}
+
+func (e missingModulesError) Error() string {
+	return fmt.Sprintf("'go %s' needs modules that GOPROXY does not provide:\n  %s", strings.Join(e.args, " "), strings.Join(e.modules, "\n  "))
+}
+
+func (e missingModulesError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,150 @@
+package gomodvendor_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testModuleProxy(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		bindingPath string
+		tmpDir      string
+		binding     servicebindings.Binding
+	)
+
+	newBinding := func() servicebindings.Binding {
+		entries, err := os.ReadDir(bindingPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		binding := servicebindings.Binding{
+			Name:    "some-binding",
+			Path:    bindingPath,
+			Type:    "go-mod-cache",
+			Entries: map[string]*servicebindings.Entry{},
+		}
+		for _, entry := range entries {
+			binding.Entries[entry.Name()] = servicebindings.NewEntry(filepath.Join(bindingPath, entry.Name()))
+		}
+
+		return binding
+	}
+
+	it.Before(func() {
+		var err error
+		bindingPath, err = os.MkdirTemp("", "binding")
+		Expect(err).NotTo(HaveOccurred())
+
+		tmpDir, err = os.MkdirTemp("", "tmp")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(bindingPath, "type"), []byte("go-mod-cache"), 0600)).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(bindingPath)).To(Succeed())
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	context("ModuleProxyDir", func() {
+		context("when the binding is in GOPROXY layout", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(bindingPath, "github.com", "some", "dependency", "@v"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(bindingPath, "github.com", "some", "dependency", "@v", "v1.2.3.zip"), nil, 0600)).To(Succeed())
+				binding = newBinding()
+			})
+
+			it("returns the binding directory", func() {
+				dir, err := gomodvendor.ModuleProxyDir(binding, tmpDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dir).To(Equal(bindingPath))
+			})
+		})
+
+		context("when the binding is a copy of a module cache", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(bindingPath, "cache", "download", "github.com", "some", "dependency", "@v"), os.ModePerm)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(bindingPath, "github.com", "some", "dependency@v1.2.3"), os.ModePerm)).To(Succeed())
+				binding = newBinding()
+			})
+
+			it("returns its cache/download directory", func() {
+				dir, err := gomodvendor.ModuleProxyDir(binding, tmpDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dir).To(Equal(filepath.Join(bindingPath, "cache", "download")))
+			})
+		})
+
+		context("when the binding is a tarball", func() {
+			it.Before(func() {
+				file, err := os.Create(filepath.Join(bindingPath, "modules.tgz"))
+				Expect(err).NotTo(HaveOccurred())
+
+				gw := gzip.NewWriter(file)
+				tw := tar.NewWriter(gw)
+
+				for _, dir := range []string{"cache/", "cache/download/", "cache/download/github.com/", "cache/download/github.com/some/", "cache/download/github.com/some/dependency/", "cache/download/github.com/some/dependency/@v/"} {
+					Expect(tw.WriteHeader(&tar.Header{Name: dir, Mode: 0755, Typeflag: tar.TypeDir})).To(Succeed())
+				}
+
+				content := []byte("v1.2.3\n")
+				Expect(tw.WriteHeader(&tar.Header{Name: "cache/download/github.com/some/dependency/@v/list", Mode: 0644, Size: int64(len(content))})).To(Succeed())
+				_, err = tw.Write(content)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(tw.Close()).To(Succeed())
+				Expect(gw.Close()).To(Succeed())
+				Expect(file.Close()).To(Succeed())
+
+				binding = newBinding()
+			})
+
+			it("extracts it and returns the directory in GOPROXY layout", func() {
+				dir, err := gomodvendor.ModuleProxyDir(binding, tmpDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dir).To(Equal(filepath.Join(tmpDir, "some-binding", "cache", "download")))
+
+				content, err := os.ReadFile(filepath.Join(dir, "github.com", "some", "dependency", "@v", "list"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("v1.2.3\n"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the binding holds no modules", func() {
+				it.Before(func() {
+					Expect(os.MkdirAll(filepath.Join(bindingPath, "github.com", "some", "dependency"), os.ModePerm)).To(Succeed())
+					binding = newBinding()
+				})
+
+				it("returns an error", func() {
+					_, err := gomodvendor.ModuleProxyDir(binding, tmpDir)
+					Expect(err).To(MatchError("the 'some-binding' binding does not contain modules in GOPROXY layout, such as <module>/@v/<version>.zip"))
+				})
+			})
+
+			context("when the tarball cannot be extracted", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(tmpDir, "some-binding"), nil, 0600)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(bindingPath, "modules.tgz"), []byte("not a tarball"), 0600)).To(Succeed())
+					binding = newBinding()
+				})
+
+				it("returns an error", func() {
+					_, err := gomodvendor.ModuleProxyDir(binding, tmpDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to extract the 'some-binding' binding")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package gomodvendor
+
+import "fmt"
+
+// VendoredModule is a module entry of a vendor/modules.txt file, as written
+// by go mod vendor.
+type VendoredModule struct {
+	ModuleVersion
+	Replacement ModuleVersion
+	Explicit    bool
+	GoVersion   string
+	Packages    []string
+}
+
+// ModulesTxt is the structured content of a vendor/modules.txt file. A
+// replacement that go mod vendor records without vendoring the module, such
+// as "# path => replacement" or "# path v1.0.0 => replacement" for an unused
+// version, is listed without packages or explicit marker.
+type ModulesTxt struct {
+	Path    string
+	Modules []VendoredModule
+}
+
+// explicitVersion is the first go version whose vendor/modules.txt records
+// which modules are explicitly required by go.mod.
+var explicitVersion = GoVersion{Major: 1, Minor: 14}
+
+// Diff compares the vendored modules against the require and replace
+// directives of go.mod, following the consistency checks that go build
+// applies with -mod=vendor. It returns one line per inconsistent module.
+func (m ModulesTxt) Diff(goMod GoMod) []string {
+	checkExplicit := true
+	if goMod.Go.Value != "" {
+		version, err := ParseGoVersion(goMod.Go.Value)
+		if err == nil && version.Compare(explicitVersion) < 0 {
+			checkExplicit = false
+		}
+	}
+
+	vendored := m.vendored()
+
+	var diff []string
+	required := map[string]bool{}
+	for _, r := range goMod.Require {
+		required[r.Path] = true
+
+		module, ok := vendored[r.Path]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("%s: go.mod requires %s, but it is missing from vendor/modules.txt", r.Path, r.Version))
+		case module.Version != r.Version:
+			diff = append(diff, fmt.Sprintf("%s: go.mod requires %s, but vendor/modules.txt has %s", r.Path, r.Version, module.Version))
+		case checkExplicit && !module.Explicit:
+			diff = append(diff, fmt.Sprintf("%s: go.mod requires %s, but it is not marked as explicit in vendor/modules.txt", r.Path, r.Version))
+		}
+	}
+
+	if checkExplicit {
+		for _, module := range m.Modules {
+			if module.Explicit && !required[module.Path] {
+				diff = append(diff, fmt.Sprintf("%s: marked as explicit in vendor/modules.txt, but not required by go.mod", module.Path))
+			}
+		}
+	}
+
+	replaced := map[string]bool{}
+	for _, r := range goMod.Replace {
+		replaced[r.Old.Path] = true
+
+		replacement, ok := m.replacement(r.Old)
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("%s: go.mod replaces it with %s, but vendor/modules.txt does not", r.Old, r.New))
+		case replacement != r.New:
+			diff = append(diff, fmt.Sprintf("%s: go.mod replaces it with %s, but vendor/modules.txt has %s", r.Old, r.New, replacement))
+		}
+	}
+
+	for _, module := range m.Modules {
+		if module.Replacement.Path != "" && !replaced[module.Path] {
+			diff = append(diff, fmt.Sprintf("%s: vendor/modules.txt replaces it with %s, but go.mod does not", module.ModuleVersion, module.Replacement))
+		}
+	}
+
+	return diff
+}
+
+// vendored returns the vendored modules by path, leaving out the
+// replacements recorded for versions that are not used.
+func (m ModulesTxt) vendored() map[string]VendoredModule {
+	vendored := map[string]VendoredModule{}
+	for _, module := range m.Modules {
+		if module.Vendored() {
+			vendored[module.Path] = module
+		}
+	}
+
+	return vendored
+}
+
+// replacement returns the replacement recorded for the given module. A
+// replacement without a version applies to every version of the module.
+func (m ModulesTxt) replacement(old ModuleVersion) (ModuleVersion, bool) {
+	for _, module := range m.Modules {
+		if module.Path != old.Path || module.Replacement.Path == "" {
+			continue
+		}
+
+		if old.Version == "" || module.Version == old.Version {
+			return module.Replacement, true
+		}
+	}
+
+	return ModuleVersion{}, false
+}
+
+// ModuleChange is a module whose vendored version or replacement changed.
+type ModuleChange struct {
+	Old VendoredModule
+	New VendoredModule
+}
+
+// VendorChanges lists the modules that differ between two vendor
+// directories.
+type VendorChanges struct {
+	Added   []VendoredModule
+	Removed []VendoredModule
+	Changed []ModuleChange
+}
+
+// Compare returns the modules that were added, removed or changed compared to
+// a previous modules.txt. Replacements that are not vendored are ignored.
+func (m ModulesTxt) Compare(previous ModulesTxt) VendorChanges {
+	old := previous.vendored()
+
+	var changes VendorChanges
+	current := map[string]bool{}
+	for _, module := range m.Modules {
+		if !module.Vendored() {
+			continue
+		}
+		current[module.Path] = true
+
+		oldModule, ok := old[module.Path]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, module)
+		case oldModule.Version != module.Version || oldModule.Replacement != module.Replacement:
+			changes.Changed = append(changes.Changed, ModuleChange{Old: oldModule, New: module})
+		}
+	}
+
+	for _, module := range previous.Modules {
+		if module.Vendored() && !current[module.Path] {
+			changes.Removed = append(changes.Removed, module)
+		}
+	}
+
+	return changes
+}
+
+// Empty reports whether no module changed.
+func (c VendorChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// Vendored reports whether the entry is a module of the build list rather
+// than a replacement that go mod vendor only records. Such a module has
+// vendored packages or is explicitly required by go.mod.
+func (v VendoredModule) Vendored() bool {
+	return v.Version != "" && (v.Explicit || len(v.Packages) > 0)
+}
+
+func (v VendoredModule) String() string {
+	return fmt.Sprintf("%s %s", v.Path, v.Resolved())
+}
+
+// Resolved returns the vendored version, followed by its replacement if any.
+func (v VendoredModule) Resolved() string {
+	if v.Replacement.Path == "" {
+		return v.Version
+	}
+
+	return fmt.Sprintf("%s => %s", v.Version, v.Replacement)
+}
@@ -0,0 +1,226 @@
+package gomodvendor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testModulesTxt(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		goMod      gomodvendor.GoMod
+		modulesTxt gomodvendor.ModulesTxt
+	)
+
+	it.Before(func() {
+		goMod = gomodvendor.GoMod{
+			Go: gomodvendor.Directive{Value: "1.21"},
+			Require: []gomodvendor.Require{
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"}},
+				{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"}, Indirect: true},
+			},
+			Replace: []gomodvendor.Replace{
+				{
+					Old: gomodvendor.ModuleVersion{Path: "github.com/some/dependency"},
+					New: gomodvendor.ModuleVersion{Path: "../dependency"},
+				},
+			},
+		}
+
+		modulesTxt = gomodvendor.ModulesTxt{
+			Modules: []gomodvendor.VendoredModule{
+				{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"},
+					Replacement:   gomodvendor.ModuleVersion{Path: "../dependency"},
+					Explicit:      true,
+					Packages:      []string{"github.com/some/dependency"},
+				},
+				{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"},
+					Explicit:      true,
+					Packages:      []string{"github.com/some-other/dependency"},
+				},
+				{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/transitive/dependency", Version: "v2.0.0"},
+					Packages:      []string{"github.com/transitive/dependency"},
+				},
+			},
+		}
+	})
+
+	context("Diff", func() {
+		it("returns nothing when modules.txt matches go.mod", func() {
+			Expect(modulesTxt.Diff(goMod)).To(BeEmpty())
+		})
+
+		context("when versions and markers differ", func() {
+			it.Before(func() {
+				goMod.Require[0].Version = "v1.3.0"
+				goMod.Require = append(goMod.Require, gomodvendor.Require{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/new/dependency", Version: "v0.1.0"},
+				})
+
+				modulesTxt.Modules[1].Explicit = false
+				modulesTxt.Modules[2].Explicit = true
+			})
+
+			it("lists every inconsistent module", func() {
+				Expect(modulesTxt.Diff(goMod)).To(Equal([]string{
+					"github.com/some/dependency: go.mod requires v1.3.0, but vendor/modules.txt has v1.2.3",
+					"github.com/some-other/dependency: go.mod requires v0.0.4, but it is not marked as explicit in vendor/modules.txt",
+					"github.com/new/dependency: go.mod requires v0.1.0, but it is missing from vendor/modules.txt",
+					"github.com/transitive/dependency: marked as explicit in vendor/modules.txt, but not required by go.mod",
+				}))
+			})
+		})
+
+		context("when replacements differ", func() {
+			it.Before(func() {
+				goMod.Replace[0].New = gomodvendor.ModuleVersion{Path: "github.com/fork/dependency", Version: "v1.2.4"}
+				goMod.Replace = append(goMod.Replace, gomodvendor.Replace{
+					Old: gomodvendor.ModuleVersion{Path: "github.com/other/dependency", Version: "v1.0.0"},
+					New: gomodvendor.ModuleVersion{Path: "../other"},
+				})
+
+				modulesTxt.Modules = append(modulesTxt.Modules, gomodvendor.VendoredModule{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/removed/dependency"},
+					Replacement:   gomodvendor.ModuleVersion{Path: "../removed"},
+				})
+			})
+
+			it("lists every inconsistent replacement", func() {
+				Expect(modulesTxt.Diff(goMod)).To(Equal([]string{
+					"github.com/some/dependency: go.mod replaces it with github.com/fork/dependency v1.2.4, but vendor/modules.txt has ../dependency",
+					"github.com/other/dependency v1.0.0: go.mod replaces it with ../other, but vendor/modules.txt does not",
+					"github.com/removed/dependency: vendor/modules.txt replaces it with ../removed, but go.mod does not",
+				}))
+			})
+		})
+
+		context("when go.mod replaces a version that is not used", func() {
+			var dir string
+
+			// go mod vendor records the replacement of a version that is not in
+			// the build list after the vendored modules, without packages.
+			it.Before(func() {
+				var err error
+				dir, err = os.MkdirTemp("", "modules-txt")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(dir, "go.mod"), []byte(`module example.com/app
+
+go 1.22
+
+require example.com/x v1.2.0
+
+replace example.com/x v1.2.0 => ./x2
+
+replace example.com/x v1.0.0 => ./x1
+`), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(dir, "modules.txt"), []byte(`# example.com/x v1.2.0 => ./x2
+## explicit; go 1.22
+example.com/x
+# example.com/x v1.0.0 => ./x1
+`), 0600)).To(Succeed())
+
+				parser := gomodvendor.NewGoModParser()
+
+				goMod, err = parser.Parse(filepath.Join(dir, "go.mod"))
+				Expect(err).NotTo(HaveOccurred())
+
+				modulesTxt, err = parser.ParseModulesTxt(filepath.Join(dir, "modules.txt"))
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.RemoveAll(dir)).To(Succeed())
+			})
+
+			it("returns nothing", func() {
+				Expect(modulesTxt.Diff(goMod)).To(BeEmpty())
+			})
+		})
+
+		context("when go.mod declares a go version older than 1.14", func() {
+			it.Before(func() {
+				goMod.Go.Value = "1.13"
+				for i := range modulesTxt.Modules {
+					modulesTxt.Modules[i].Explicit = false
+				}
+			})
+
+			it("does not check the explicit markers", func() {
+				Expect(modulesTxt.Diff(goMod)).To(BeEmpty())
+			})
+		})
+	})
+
+	context("Compare", func() {
+		it("lists the added, removed and changed modules", func() {
+			previous := gomodvendor.ModulesTxt{
+				Modules: []gomodvendor.VendoredModule{
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.0"}, Explicit: true},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"}, Explicit: true},
+					{ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/removed/dependency", Version: "v0.1.0"}, Packages: []string{"github.com/removed/dependency"}},
+				},
+			}
+
+			changes := modulesTxt.Compare(previous)
+			Expect(changes.Empty()).To(BeFalse())
+			Expect(changes.Added).To(Equal([]gomodvendor.VendoredModule{modulesTxt.Modules[2]}))
+			Expect(changes.Removed).To(Equal([]gomodvendor.VendoredModule{previous.Modules[2]}))
+			Expect(changes.Changed).To(Equal([]gomodvendor.ModuleChange{
+				{Old: previous.Modules[0], New: modulesTxt.Modules[0]},
+			}))
+
+			Expect(changes.Changed[0].Old.Resolved()).To(Equal("v1.2.0"))
+			Expect(changes.Changed[0].New.Resolved()).To(Equal("v1.2.3 => ../dependency"))
+			Expect(changes.Added[0].String()).To(Equal("github.com/transitive/dependency v2.0.0"))
+		})
+
+		it("is empty when nothing changed", func() {
+			Expect(modulesTxt.Compare(modulesTxt).Empty()).To(BeTrue())
+		})
+
+		context("when modules.txt replaces a version that is not used", func() {
+			var dir string
+
+			it.Before(func() {
+				var err error
+				dir, err = os.MkdirTemp("", "modules-txt")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(dir, "modules.txt"), []byte(`# example.com/x v1.2.0 => ./x2
+## explicit; go 1.22
+example.com/x
+# example.com/x v1.0.0 => ./x1
+`), 0600)).To(Succeed())
+
+				modulesTxt, err = gomodvendor.NewGoModParser().ParseModulesTxt(filepath.Join(dir, "modules.txt"))
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it.After(func() {
+				Expect(os.RemoveAll(dir)).To(Succeed())
+			})
+
+			it("does not report it as a change", func() {
+				previous := gomodvendor.ModulesTxt{
+					Modules: []gomodvendor.VendoredModule{
+						modulesTxt.Modules[0],
+					},
+				}
+
+				changes := modulesTxt.Compare(previous)
+				Expect(changes.Empty()).To(BeTrue())
+			})
+		})
+	})
+}
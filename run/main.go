@@ -7,28 +7,25 @@ import (
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/chronos"
 	"github.com/paketo-buildpacks/packit/v2/pexec"
-	"github.com/paketo-buildpacks/packit/v2/sbom"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 )
 
-type SBOMGenerator struct{}
-
-func (s SBOMGenerator) Generate(path string) (sbom.SBOM, error) {
-	return sbom.Generate(path)
-}
-
 func main() {
 	logEmitter := scribe.NewEmitter(os.Stdout).WithLevel(os.Getenv("BP_LOG_LEVEL"))
+	configParser := gomodvendor.NewBuildConfigurationParser()
 	goModParser := gomodvendor.NewGoModParser()
-	sbomGenerator := SBOMGenerator{}
+	sbomGenerator := gomodvendor.NewModuleSBOMGenerator()
 
 	packit.Run(
-		gomodvendor.Detect(goModParser),
+		gomodvendor.Detect(configParser, goModParser, logEmitter),
 		gomodvendor.Build(
-			gomodvendor.NewModVendor(pexec.NewExecutable("go"), logEmitter, chronos.DefaultClock),
+			configParser,
+			gomodvendor.NewModVendor(pexec.NewExecutable("go"), goModParser, logEmitter, chronos.DefaultClock),
 			logEmitter,
 			chronos.DefaultClock,
 			sbomGenerator,
+			servicebindings.NewResolver(),
 		),
 	)
 }
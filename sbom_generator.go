@@ -0,0 +1,306 @@
+package gomodvendor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/pkg"
+	syftsbom "github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+)
+
+// ModuleSBOMGenerator generates a single SBOM from one or more go.mod files or
+// directories, so that every vendored module contributes its own entries.
+// Modules replaced by a directory of the app are listed as local components,
+// and modules that only provide tools are marked with a tool scope.
+type ModuleSBOMGenerator struct{}
+
+func NewModuleSBOMGenerator() ModuleSBOMGenerator {
+	return ModuleSBOMGenerator{}
+}
+
+// Generate scans every path with sbom.Generate. A single path without local
+// or tool modules is returned as scanned. Otherwise the SBOMs are merged: the
+// source of the merged SBOM is the directory holding every path, and the
+// locations found in each path are made relative to it.
+func (g ModuleSBOMGenerator) Generate(paths []string, localModules []LocalReplacement, toolModules []string) (sbom.SBOM, error) {
+	if len(paths) == 0 {
+		return sbom.SBOM{}, nil
+	}
+
+	if len(paths) == 1 && len(localModules) == 0 && len(toolModules) == 0 {
+		return sbom.Generate(paths[0])
+	}
+
+	var boms []*syftsbom.SBOM
+	var dirs []string
+	for _, path := range paths {
+		bom, err := scan(path)
+		if err != nil {
+			return sbom.SBOM{}, err
+		}
+
+		dir, err := scanDir(path)
+		if err != nil {
+			return sbom.SBOM{}, err
+		}
+
+		boms = append(boms, bom)
+		dirs = append(dirs, dir)
+	}
+
+	merged := boms[0]
+	if len(boms) > 1 {
+		var err error
+		merged, err = mergeSBOMs(boms, dirs)
+		if err != nil {
+			return sbom.SBOM{}, err
+		}
+	}
+
+	addLocalModules(merged, localModules)
+	markToolModules(merged, toolModules)
+
+	return sbom.NewSBOM(*merged), nil
+}
+
+// mergeSBOMs combines the SBOMs scanned from the given directories into one
+// whose source is their common parent directory.
+func mergeSBOMs(boms []*syftsbom.SBOM, dirs []string) (*syftsbom.SBOM, error) {
+	root := commonDir(dirs)
+
+	src, err := syft.GetSource(context.Background(), root, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	merged := &syftsbom.SBOM{
+		Artifacts: syftsbom.Artifacts{
+			Packages:     pkg.NewCollection(),
+			FileMetadata: map[file.Coordinates]file.Metadata{},
+			FileDigests:  map[file.Coordinates][]file.Digest{},
+		},
+		Source:     src.Describe(),
+		Descriptor: boms[0].Descriptor,
+	}
+
+	for i, bom := range boms {
+		rel, err := filepath.Rel(root, dirs[i])
+		if err != nil {
+			return nil, err
+		}
+		prefix := path.Join("/", filepath.ToSlash(rel))
+
+		for _, p := range bom.Artifacts.Packages.Sorted() {
+			var locations []file.Location
+			for _, location := range p.Locations.ToSlice() {
+				locations = append(locations, rebaseLocation(location, prefix))
+			}
+
+			p.Locations = file.NewLocationSet(locations...)
+			merged.Artifacts.Packages.Add(p)
+		}
+
+		for coordinates, metadata := range bom.Artifacts.FileMetadata {
+			merged.Artifacts.FileMetadata[rebaseCoordinates(coordinates, prefix)] = metadata
+		}
+
+		for coordinates, digests := range bom.Artifacts.FileDigests {
+			merged.Artifacts.FileDigests[rebaseCoordinates(coordinates, prefix)] = digests
+		}
+
+		for _, relationship := range bom.Relationships {
+			relationship.From = rebaseIdentifiable(relationship.From, bom.Source.ID, src, prefix)
+			relationship.To = rebaseIdentifiable(relationship.To, bom.Source.ID, src, prefix)
+			merged.Relationships = append(merged.Relationships, relationship)
+		}
+	}
+
+	return merged, nil
+}
+
+// rebaseIdentifiable points a relationship that referred to the source of a
+// scanned SBOM, or to one of its files, at the merged SBOM.
+func rebaseIdentifiable(identifiable artifact.Identifiable, sourceID string, src source.Source, prefix string) artifact.Identifiable {
+	switch identifiable := identifiable.(type) {
+	case file.Coordinates:
+		return rebaseCoordinates(identifiable, prefix)
+	case file.Location:
+		return rebaseLocation(identifiable, prefix)
+	}
+
+	if string(identifiable.ID()) == sourceID {
+		return src
+	}
+
+	return identifiable
+}
+
+func rebaseLocation(location file.Location, prefix string) file.Location {
+	// Locations read from the same file share their annotations, so they are
+	// copied before the location is changed.
+	annotations := maps.Clone(location.Annotations)
+	location = location.WithoutAnnotations()
+	maps.Copy(location.Annotations, annotations)
+
+	location.Coordinates = rebaseCoordinates(location.Coordinates, prefix)
+	if location.AccessPath != "" {
+		location.AccessPath = path.Join(prefix, location.AccessPath)
+	}
+
+	return location
+}
+
+func rebaseCoordinates(coordinates file.Coordinates, prefix string) file.Coordinates {
+	coordinates.RealPath = path.Join(prefix, coordinates.RealPath)
+	return coordinates
+}
+
+// scanDir returns the directory that the locations found in the given path
+// are relative to: the path itself, or the directory holding a scanned file.
+func scanDir(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if info.IsDir() {
+		return path, nil
+	}
+
+	return filepath.Dir(path), nil
+}
+
+// commonDir returns the deepest directory that holds every given directory.
+func commonDir(dirs []string) string {
+	root := filepath.Clean(dirs[0])
+	for _, dir := range dirs[1:] {
+		dir = filepath.Clean(dir)
+		for {
+			rel, err := filepath.Rel(root, dir)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				break
+			}
+
+			root = filepath.Dir(root)
+		}
+	}
+
+	return root
+}
+
+// addLocalModules replaces the entries of modules replaced by a directory
+// with a single entry each. Like go version -m, the version of a local module
+// is reported as (devel), and its location is the go.mod file of the
+// directory.
+func addLocalModules(bom *syftsbom.SBOM, localModules []LocalReplacement) {
+	removed := map[artifact.ID]bool{}
+	added := map[string]bool{}
+	for _, local := range localModules {
+		for _, p := range bom.Artifacts.Packages.PackagesByName(local.Old.Path) {
+			if p.Type == pkg.GoModulePkg {
+				bom.Artifacts.Packages.Delete(p.ID())
+				removed[p.ID()] = true
+			}
+		}
+
+		if added[local.Old.Path] {
+			continue
+		}
+		added[local.Old.Path] = true
+
+		p := pkg.Package{
+			Name:      local.Old.Path,
+			Version:   "(devel)",
+			Locations: file.NewLocationSet(file.NewLocation(path.Join("/", local.Dir, GoModLocation))),
+			PURL:      "pkg:golang/" + local.Old.Path,
+			Language:  pkg.Go,
+			Type:      pkg.GoModulePkg,
+			Metadata:  pkg.GolangModuleEntry{},
+		}
+		p.SetID()
+
+		bom.Artifacts.Packages.Add(p)
+	}
+
+	var relationships []artifact.Relationship
+	for _, relationship := range bom.Relationships {
+		if removed[relationship.From.ID()] || removed[relationship.To.ID()] {
+			continue
+		}
+
+		relationships = append(relationships, relationship)
+	}
+	bom.Relationships = relationships
+}
+
+// The locations of modules that are only needed to run the tools declared in
+// go.mod, rather than at runtime, carry the tool scope annotation.
+const (
+	ScopeAnnotation = "scope"
+	ToolScope       = "tool"
+)
+
+// markToolModules annotates the locations of the given modules with the tool
+// scope. The package ID is kept so that relationships still refer to it.
+func markToolModules(bom *syftsbom.SBOM, toolModules []string) {
+	for _, module := range toolModules {
+		for _, p := range bom.Artifacts.Packages.PackagesByName(module) {
+			if p.Type != pkg.GoModulePkg {
+				continue
+			}
+
+			// Packages found in the same go.mod file share the annotations of
+			// its location, so they are copied before being changed.
+			var locations []file.Location
+			for _, location := range p.Locations.ToSlice() {
+				annotations := maps.Clone(location.Annotations)
+				location = location.WithoutAnnotations()
+				maps.Copy(location.Annotations, annotations)
+
+				locations = append(locations, location.WithAnnotation(ScopeAnnotation, ToolScope))
+			}
+
+			bom.Artifacts.Packages.Delete(p.ID())
+			p.Locations = file.NewLocationSet(locations...)
+			bom.Artifacts.Packages.Add(p)
+		}
+	}
+}
+
+// scan reads the syft SBOM generated by sbom.Generate for the given path,
+// so that it is scanned exactly as packit scans a single path.
+func scan(path string) (*syftsbom.SBOM, error) {
+	bom, err := sbom.Generate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	formatter, err := bom.InFormats(sbom.SyftFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	formats := formatter.Formats()
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("failed to format the SBOM of %s", path)
+	}
+
+	decoded, _, _, err := syftjson.NewFormatDecoder().Decode(formats[0].Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the SBOM of %s: %w", path, err)
+	}
+
+	return decoded, nil
+}
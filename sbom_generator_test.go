@@ -0,0 +1,217 @@
+package gomodvendor_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/paketo-buildpacks/packit/v2/sbom"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testModuleSBOMGenerator(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		generator  gomodvendor.ModuleSBOMGenerator
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte(`module example.com/server
+
+go 1.22
+
+require github.com/some/dependency v0.3.1
+`), 0600)).To(Succeed())
+
+		Expect(os.MkdirAll(filepath.Join(workingDir, "tools"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "tools", "go.mod"), []byte(`module example.com/tools
+
+go 1.22
+
+require github.com/some-other/dependency v1.2.3
+`), 0600)).To(Succeed())
+
+		generator = gomodvendor.NewModuleSBOMGenerator()
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	it("generates a single SBOM with the entries of every module", func() {
+		bom, err := generator.Generate([]string{
+			filepath.Join(workingDir, "go.mod"),
+			filepath.Join(workingDir, "tools", "go.mod"),
+		}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		formatter, err := bom.InFormats("application/vnd.syft+json")
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := io.ReadAll(formatter.Formats()[0].Content)
+		Expect(err).NotTo(HaveOccurred())
+
+		var document struct {
+			Artifacts []struct {
+				Name      string `json:"name"`
+				Locations []struct {
+					Path string `json:"path"`
+				} `json:"locations"`
+			} `json:"artifacts"`
+			Source struct {
+				Type     string `json:"type"`
+				Metadata struct {
+					Path string `json:"path"`
+				} `json:"metadata"`
+			} `json:"source"`
+		}
+		Expect(json.Unmarshal(content, &document)).To(Succeed())
+
+		locations := map[string]string{}
+		for _, artifact := range document.Artifacts {
+			for _, location := range artifact.Locations {
+				locations[artifact.Name] = location.Path
+			}
+		}
+
+		Expect(locations).To(Equal(map[string]string{
+			"github.com/some/dependency":       "/go.mod",
+			"github.com/some-other/dependency": "/tools/go.mod",
+		}))
+		Expect(document.Source.Type).To(Equal("directory"))
+		Expect(document.Source.Metadata.Path).To(Equal(workingDir))
+	})
+
+	context("when a single path is scanned", func() {
+		it("generates the same SBOM as sbom.Generate", func() {
+			bom, err := generator.Generate([]string{filepath.Join(workingDir, "tools", "go.mod")}, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			expected, err := sbom.Generate(filepath.Join(workingDir, "tools", "go.mod"))
+			Expect(err).NotTo(HaveOccurred())
+
+			formatter, err := bom.InFormats("application/vnd.syft+json")
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := io.ReadAll(formatter.Formats()[0].Content)
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedFormatter, err := expected.InFormats("application/vnd.syft+json")
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedContent, err := io.ReadAll(expectedFormatter.Formats()[0].Content)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(content)).To(MatchJSON(string(expectedContent)))
+		})
+	})
+
+	context("when a module is replaced by a directory", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte(`module example.com/server
+
+go 1.22
+
+require (
+	example.com/local v0.0.0
+	github.com/some/dependency v0.3.1
+)
+
+replace example.com/local => ./libs/local
+`), 0600)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "libs", "local"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "libs", "local", "go.mod"), []byte("module example.com/local\n"), 0600)).To(Succeed())
+		})
+
+		it("lists the module as a local component", func() {
+			bom, err := generator.Generate([]string{filepath.Join(workingDir, "go.mod")}, []gomodvendor.LocalReplacement{
+				{
+					Old: gomodvendor.ModuleVersion{Path: "example.com/local"},
+					New: "./libs/local",
+					Dir: "libs/local",
+				},
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			formatter, err := bom.InFormats("application/vnd.syft+json")
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := io.ReadAll(formatter.Formats()[0].Content)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(strings.Count(string(content), `"name":"example.com/local"`)).To(Equal(1))
+			Expect(string(content)).To(ContainSubstring(`"version":"(devel)"`))
+			Expect(string(content)).To(ContainSubstring(`"path":"/libs/local/go.mod"`))
+			Expect(string(content)).To(ContainSubstring(`"name":"github.com/some/dependency"`))
+		})
+	})
+
+	context("when a module provides a tool", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte(`module example.com/server
+
+go 1.24
+
+require (
+	github.com/some/dependency v0.3.1
+	github.com/some/tool v1.0.0
+)
+
+tool github.com/some/tool/cmd/tool
+`), 0600)).To(Succeed())
+		})
+
+		it("marks the module with the tool scope", func() {
+			bom, err := generator.Generate([]string{filepath.Join(workingDir, "go.mod")}, nil, []string{"github.com/some/tool"})
+			Expect(err).NotTo(HaveOccurred())
+
+			formatter, err := bom.InFormats("application/vnd.syft+json")
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := io.ReadAll(formatter.Formats()[0].Content)
+			Expect(err).NotTo(HaveOccurred())
+
+			var document struct {
+				Artifacts []struct {
+					Name      string `json:"name"`
+					Locations []struct {
+						Annotations map[string]string `json:"annotations"`
+					} `json:"locations"`
+				} `json:"artifacts"`
+			}
+			Expect(json.Unmarshal(content, &document)).To(Succeed())
+
+			scopes := map[string]string{}
+			for _, artifact := range document.Artifacts {
+				for _, location := range artifact.Locations {
+					scopes[artifact.Name] = location.Annotations["scope"]
+				}
+			}
+
+			Expect(scopes).To(HaveKeyWithValue("github.com/some/tool", "tool"))
+			Expect(scopes).To(HaveKeyWithValue("github.com/some/dependency", ""))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when a path does not exist", func() {
+			it("returns an error", func() {
+				_, err := generator.Generate([]string{filepath.Join(workingDir, "missing", "go.mod")}, nil, nil)
+				Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+			})
+		})
+	})
+}
@@ -0,0 +1,144 @@
+package gomodvendor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// VendorDigest identifies the content of a vendor directory, both as a whole
+// and per vendored module, along with the module files it was generated from.
+// Digests use the "h1:" format of go.sum.
+type VendorDigest struct {
+	Inputs  string            `toml:"inputs" json:"inputs"`
+	Digest  string            `toml:"digest" json:"digest"`
+	Modules map[string]string `toml:"modules" json:"modules"`
+}
+
+// ComputeVendorDigest hashes every file of the vendor directory. The files
+// of each vendored module are those of the package directories listed for it
+// in modules.txt.
+func ComputeVendorDigest(vendorDir string, modulesTxt ModulesTxt) (VendorDigest, error) {
+	digest, err := dirhash.HashDir(vendorDir, "vendor", dirhash.Hash1)
+	if err != nil {
+		return VendorDigest{}, fmt.Errorf("failed to compute the vendor digest: %w", err)
+	}
+
+	vendorDigest := VendorDigest{
+		Digest:  digest,
+		Modules: map[string]string{},
+	}
+
+	for _, module := range modulesTxt.Modules {
+		if !module.Vendored() || len(module.Packages) == 0 {
+			continue
+		}
+
+		var files []string
+		for _, pkg := range module.Packages {
+			entries, err := os.ReadDir(filepath.Join(vendorDir, filepath.FromSlash(pkg)))
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return VendorDigest{}, fmt.Errorf("failed to compute the vendor digest: %w", err)
+			}
+
+			for _, entry := range entries {
+				if !entry.IsDir() {
+					files = append(files, pkg+"/"+entry.Name())
+				}
+			}
+		}
+
+		vendorDigest.Modules[module.Path], err = dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(vendorDir, filepath.FromSlash(name)))
+		})
+		if err != nil {
+			return VendorDigest{}, fmt.Errorf("failed to compute the vendor digest: %w", err)
+		}
+	}
+
+	return vendorDigest, nil
+}
+
+// ComputeInputsDigest hashes the given module files of a directory, skipping
+// those that do not exist.
+func ComputeInputsDigest(dir string, names ...string) (string, error) {
+	var files []string
+	for _, name := range names {
+		exists, err := fs.Exists(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+
+		if exists {
+			files = append(files, name)
+		}
+	}
+
+	return dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, name))
+	})
+}
+
+// Changed returns the modules whose vendored files differ from the given
+// digest. When only files outside of the vendored packages differ, such as
+// vendor/modules.txt, the vendor directory itself is reported.
+func (d VendorDigest) Changed(current VendorDigest) []string {
+	var changed []string
+	for path, digest := range current.Modules {
+		if d.Modules[path] != digest {
+			changed = append(changed, path)
+		}
+	}
+
+	for path := range d.Modules {
+		if _, ok := current.Modules[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	slices.Sort(changed)
+
+	if len(changed) == 0 && d.Digest != current.Digest {
+		changed = append(changed, "vendor")
+	}
+
+	return changed
+}
+
+// vendorDigests reads the vendor digests recorded in the layer metadata by a
+// previous build, keyed by module path. Unreadable metadata is ignored, as if
+// no digest had been recorded.
+func vendorDigests(metadata map[string]interface{}) map[string]VendorDigest {
+	digests := map[string]VendorDigest{}
+
+	value, ok := metadata["vendor-digests"]
+	if !ok {
+		return digests
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		return digests
+	}
+
+	var recorded map[string]VendorDigest
+	err = json.Unmarshal(content, &recorded)
+	if err != nil {
+		return digests
+	}
+
+	for path, digest := range recorded {
+		digests[path] = digest
+	}
+
+	return digests
+}
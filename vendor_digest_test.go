@@ -0,0 +1,187 @@
+package gomodvendor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testVendorDigest(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		vendorDir  string
+		modulesTxt gomodvendor.ModulesTxt
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		vendorDir = filepath.Join(workingDir, "vendor")
+		Expect(os.MkdirAll(filepath.Join(vendorDir, "github.com", "some", "dependency", "pkg"), os.ModePerm)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(vendorDir, "github.com", "some-other", "dependency"), os.ModePerm)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(vendorDir, "modules.txt"), []byte("# some modules.txt\n"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"), []byte("package dependency\n"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "pkg", "pkg.go"), []byte("package pkg\n"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some-other", "dependency", "dependency.go"), []byte("package dependency\n"), os.ModePerm)).To(Succeed())
+
+		modulesTxt = gomodvendor.ModulesTxt{
+			Modules: []gomodvendor.VendoredModule{
+				{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.2.3"},
+					Packages:      []string{"github.com/some/dependency", "github.com/some/dependency/pkg"},
+				},
+				{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some-other/dependency", Version: "v0.0.4"},
+					Packages:      []string{"github.com/some-other/dependency"},
+				},
+			},
+		}
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("ComputeVendorDigest", func() {
+		it("digests the vendor directory and each vendored module", func() {
+			digest, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(digest.Digest).To(HavePrefix("h1:"))
+			Expect(digest.Modules).To(HaveLen(2))
+			Expect(digest.Modules).To(HaveKeyWithValue("github.com/some/dependency", HavePrefix("h1:")))
+			Expect(digest.Modules).To(HaveKeyWithValue("github.com/some-other/dependency", HavePrefix("h1:")))
+
+			again, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(again).To(Equal(digest))
+		})
+
+		context("when a vendored file is edited", func() {
+			it("only changes the digest of its module", func() {
+				before, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "pkg", "pkg.go"), []byte("package pkg // patched\n"), os.ModePerm)).To(Succeed())
+
+				after, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(after.Digest).NotTo(Equal(before.Digest))
+				Expect(after.Modules["github.com/some/dependency"]).NotTo(Equal(before.Modules["github.com/some/dependency"]))
+				Expect(after.Modules["github.com/some-other/dependency"]).To(Equal(before.Modules["github.com/some-other/dependency"]))
+				Expect(before.Changed(after)).To(Equal([]string{"github.com/some/dependency"}))
+			})
+		})
+
+		context("when a file outside of the vendored packages is added", func() {
+			it("reports the vendor directory as changed", func() {
+				before, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(vendorDir, "README.md"), nil, os.ModePerm)).To(Succeed())
+
+				after, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(after.Digest).NotTo(Equal(before.Digest))
+				Expect(before.Changed(after)).To(Equal([]string{"vendor"}))
+			})
+		})
+
+		context("when modules.txt records a replacement of an unused version", func() {
+			it.Before(func() {
+				modulesTxt.Modules = append(modulesTxt.Modules, gomodvendor.VendoredModule{
+					ModuleVersion: gomodvendor.ModuleVersion{Path: "github.com/some/dependency", Version: "v1.0.0"},
+					Replacement:   gomodvendor.ModuleVersion{Path: "./dependency-v1"},
+				})
+			})
+
+			it("keeps the digest of the vendored module", func() {
+				before, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"), []byte("package dependency // patched\n"), os.ModePerm)).To(Succeed())
+
+				after, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(before.Changed(after)).To(Equal([]string{"github.com/some/dependency"}))
+			})
+		})
+
+		context("when the vendor directory cannot be read", func() {
+			it.Before(func() {
+				Expect(os.Chmod(filepath.Join(vendorDir, "github.com", "some-other"), 0000)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(filepath.Join(vendorDir, "github.com", "some-other"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := gomodvendor.ComputeVendorDigest(vendorDir, modulesTxt)
+				Expect(err).To(MatchError(ContainSubstring("failed to compute the vendor digest")))
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+	})
+
+	context("ComputeInputsDigest", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.mod"), []byte("module some-module\n"), os.ModePerm)).To(Succeed())
+		})
+
+		it("digests the module files that exist", func() {
+			digest, err := gomodvendor.ComputeInputsDigest(workingDir, "go.mod", "go.sum")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digest).To(HavePrefix("h1:"))
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "go.sum"), []byte("some-sum\n"), os.ModePerm)).To(Succeed())
+
+			changed, err := gomodvendor.ComputeInputsDigest(workingDir, "go.mod", "go.sum")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).NotTo(Equal(digest))
+		})
+	})
+
+	context("Changed", func() {
+		it("returns the modules that were added, removed or changed", func() {
+			stored := gomodvendor.VendorDigest{
+				Digest: "h1:old=",
+				Modules: map[string]string{
+					"github.com/some/dependency":       "h1:abc=",
+					"github.com/some-other/dependency": "h1:def=",
+				},
+			}
+
+			current := gomodvendor.VendorDigest{
+				Digest: "h1:new=",
+				Modules: map[string]string{
+					"github.com/some/dependency": "h1:abc=",
+					"github.com/new/dependency":  "h1:ghi=",
+				},
+			}
+
+			Expect(stored.Changed(current)).To(Equal([]string{
+				"github.com/new/dependency",
+				"github.com/some-other/dependency",
+			}))
+		})
+
+		it("returns nothing when the digests are the same", func() {
+			digest := gomodvendor.VendorDigest{Digest: "h1:abc=", Modules: map[string]string{"github.com/some/dependency": "h1:def="}}
+			Expect(digest.Changed(digest)).To(BeEmpty())
+		})
+	})
+}
@@ -0,0 +1,181 @@
+package gomodvendor
+
+import (
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+// RestoreVendorSnapshot recreates a vendor directory from a snapshot taken
+// by SaveVendorSnapshot. It is not ok when there is no snapshot, or when the
+// vendor directory already exists and is left alone.
+func RestoreVendorSnapshot(snapshotDir, vendorDir string) (bool, error) {
+	exists, err := fs.Exists(snapshotDir)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists {
+		return false, nil
+	}
+
+	exists, err = fs.Exists(vendorDir)
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		return false, nil
+	}
+
+	err = linkTree(snapshotDir, vendorDir)
+	if err != nil {
+		// A partially restored vendor directory would be trusted by the go
+		// command, so it is removed before vendoring again.
+		if removeErr := os.RemoveAll(vendorDir); removeErr != nil {
+			return false, fmt.Errorf("failed to restore the vendor snapshot: %w", removeErr)
+		}
+
+		return false, fmt.Errorf("failed to restore the vendor snapshot: %w", err)
+	}
+
+	return true, nil
+}
+
+// SaveVendorSnapshot replaces the snapshot with the content of the vendor
+// directory.
+func SaveVendorSnapshot(vendorDir, snapshotDir string) error {
+	err := os.RemoveAll(snapshotDir)
+	if err != nil {
+		return fmt.Errorf("failed to save the vendor snapshot: %w", err)
+	}
+
+	err = linkTree(vendorDir, snapshotDir)
+	if err != nil {
+		return fmt.Errorf("failed to save the vendor snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// linkTree recreates the src directory at dst. Files are cloned when the
+// filesystem supports reflinks, and copied otherwise. They are never hard
+// linked, as writing to a vendored file in place would then change the
+// snapshot too.
+func linkTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, os.ModePerm)
+
+		case entry.Type()&iofs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+
+		default:
+			return linkFile(path, target)
+		}
+	})
+}
+
+func linkFile(src, dst string) error {
+	if err := cloneFile(src, dst); err == nil {
+		return nil
+	}
+
+	return copyFile(src, dst)
+}
+
+func copyFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	destination, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(destination, source)
+	if err != nil {
+		destination.Close()
+		return err
+	}
+
+	return destination.Close()
+}
+
+// pruneVendorSnapshots removes the snapshots of the modules that are no
+// longer vendored from the layer directory.
+func pruneVendorSnapshots(layerPath string, snapshots map[string]string) error {
+	keep := map[string]bool{}
+	for path := range snapshots {
+		keep[cacheNamespace(path)] = true
+	}
+
+	entries, err := os.ReadDir(layerPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if keep[entry.Name()] {
+			continue
+		}
+
+		err = os.RemoveAll(filepath.Join(layerPath, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// vendorSnapshots reads the keys of the vendor snapshots recorded in the
+// layer metadata by a previous build, keyed by module path.
+func vendorSnapshots(metadata map[string]interface{}) map[string]string {
+	snapshots := map[string]string{}
+
+	recorded, ok := metadata["vendor-snapshots"].(map[string]interface{})
+	if !ok {
+		return snapshots
+	}
+
+	for path, key := range recorded {
+		if key, ok := key.(string); ok {
+			snapshots[path] = key
+		}
+	}
+
+	return snapshots
+}
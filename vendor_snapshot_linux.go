@@ -0,0 +1,36 @@
+package gomodvendor
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile creates dst as a reflink of src, sharing its blocks until either
+// file is written.
+func cloneFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	destination, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	err = unix.IoctlFileClone(int(destination.Fd()), int(source.Fd()))
+	if err != nil {
+		destination.Close()
+		os.Remove(dst)
+		return err
+	}
+
+	return destination.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package gomodvendor
+
+import "errors"
+
+// cloneFile is only supported on Linux.
+func cloneFile(src, dst string) error {
+	return errors.ErrUnsupported
+}
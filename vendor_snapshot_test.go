@@ -0,0 +1,142 @@
+package gomodvendor_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomodvendor "github.com/paketo-buildpacks/go-mod-vendor"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testVendorSnapshot(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		vendorDir   string
+		snapshotDir string
+		tmpDir      string
+	)
+
+	it.Before(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "vendor-snapshot")
+		Expect(err).NotTo(HaveOccurred())
+
+		vendorDir = filepath.Join(tmpDir, "app", "vendor")
+		snapshotDir = filepath.Join(tmpDir, "layer", "snapshot")
+
+		Expect(os.MkdirAll(filepath.Join(vendorDir, "github.com", "some", "dependency"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(vendorDir, "modules.txt"), []byte("# github.com/some/dependency v1.2.3\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"), []byte("package dependency\n"), 0644)).To(Succeed())
+		Expect(os.Symlink("dependency.go", filepath.Join(vendorDir, "github.com", "some", "dependency", "link.go"))).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	context("SaveVendorSnapshot", func() {
+		it("copies the vendor directory into the snapshot", func() {
+			Expect(gomodvendor.SaveVendorSnapshot(vendorDir, snapshotDir)).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(snapshotDir, "github.com", "some", "dependency", "dependency.go"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("package dependency\n"))
+
+			vendorInfo, err := os.Stat(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"))
+			Expect(err).NotTo(HaveOccurred())
+			snapshotInfo, err := os.Stat(filepath.Join(snapshotDir, "github.com", "some", "dependency", "dependency.go"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.SameFile(vendorInfo, snapshotInfo)).To(BeFalse())
+
+			link, err := os.Readlink(filepath.Join(snapshotDir, "github.com", "some", "dependency", "link.go"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal("dependency.go"))
+		})
+
+		context("when a previous snapshot exists", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(snapshotDir, "github.com", "old"), os.ModePerm)).To(Succeed())
+			})
+
+			it("replaces it", func() {
+				Expect(gomodvendor.SaveVendorSnapshot(vendorDir, snapshotDir)).To(Succeed())
+
+				Expect(filepath.Join(snapshotDir, "github.com", "old")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(snapshotDir, "modules.txt")).To(BeARegularFile())
+			})
+		})
+
+		context("when the vendor directory does not exist", func() {
+			it("returns an error", func() {
+				err := gomodvendor.SaveVendorSnapshot(filepath.Join(tmpDir, "missing"), snapshotDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to save the vendor snapshot")))
+			})
+		})
+	})
+
+	context("RestoreVendorSnapshot", func() {
+		it.Before(func() {
+			Expect(gomodvendor.SaveVendorSnapshot(vendorDir, snapshotDir)).To(Succeed())
+			Expect(os.RemoveAll(vendorDir)).To(Succeed())
+		})
+
+		it("recreates the vendor directory from the snapshot", func() {
+			ok, err := gomodvendor.RestoreVendorSnapshot(snapshotDir, vendorDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			content, err := os.ReadFile(filepath.Join(vendorDir, "modules.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("# github.com/some/dependency v1.2.3\n"))
+
+			info, err := os.Stat(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0644)))
+		})
+
+		it("does not share the files with the snapshot", func() {
+			_, err := gomodvendor.RestoreVendorSnapshot(snapshotDir, vendorDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			file, err := os.OpenFile(filepath.Join(vendorDir, "github.com", "some", "dependency", "dependency.go"), os.O_WRONLY|os.O_TRUNC, 0644)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = file.WriteString("package edited\n")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(snapshotDir, "github.com", "some", "dependency", "dependency.go"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("package dependency\n"))
+		})
+
+		context("when there is no snapshot", func() {
+			it("is not ok", func() {
+				ok, err := gomodvendor.RestoreVendorSnapshot(filepath.Join(tmpDir, "missing"), vendorDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+				Expect(vendorDir).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("when the vendor directory already exists", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(vendorDir, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(vendorDir, "modules.txt"), []byte("committed\n"), 0644)).To(Succeed())
+			})
+
+			it("leaves it alone", func() {
+				ok, err := gomodvendor.RestoreVendorSnapshot(snapshotDir, vendorDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ok).To(BeFalse())
+
+				content, err := os.ReadFile(filepath.Join(vendorDir, "modules.txt"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("committed\n"))
+			})
+		})
+	})
+}